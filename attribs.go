@@ -0,0 +1,198 @@
+package mdson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttribType identifies the Go type an attribute value is converted to
+type AttribType int
+
+const (
+	TypeString AttribType = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeTime
+	TypeDuration
+	TypeList
+)
+
+func (t AttribType) String() string {
+	if t < TypeString || t > TypeList {
+		return "unknown"
+	}
+	return [...]string{"string", "int", "float", "bool", "time", "duration", "list"}[t]
+}
+
+// TimeLayouts lists the layouts tried in order when converting an attribute
+// to a time.Time, eg "2023-07-12", "2023-07-12T15:00:00Z" or "12July2023"
+var TimeLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2January2006",
+	"2Jan2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// ErrNoAttrib is returned (wrapped in an AttribError) when an attribute is not declared
+var ErrNoAttrib = errors.New("no such attribute")
+
+// ErrUnknownAttrib is returned (wrapped in an AttribError) when an attribute
+// is declared that is not expected, eg a misspelled option
+var ErrUnknownAttrib = errors.New("unknown attribute")
+
+// AttribError reports an attribute that is missing or whose value cannot be
+// converted to the requested type
+type AttribError struct {
+	Key   string
+	Line  int
+	Type  AttribType
+	Value string
+	Err   error
+}
+
+func (e *AttribError) Error() string {
+	if e.Err == ErrNoAttrib {
+		return fmt.Sprintf("attribute '%s': %s", e.Key, e.Err)
+	}
+	if e.Err == ErrUnknownAttrib {
+		return fmt.Sprintf("line %d: attribute '%s': %s", e.Line, e.Key, e.Err)
+	}
+	return fmt.Sprintf("line %d: attribute '%s': cannot convert '%s' to %s: %s",
+		e.Line, e.Key, e.Value, e.Type, e.Err)
+}
+
+func (e *AttribError) Unwrap() error {
+	return e.Err
+}
+
+// coerce converts an attribute value to the Go type corresponding to typ:
+// string, int, float64, bool, time.Time, time.Duration or []string
+func coerce(s string, typ AttribType) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch typ {
+	case TypeString:
+		return s, nil
+	case TypeInt:
+		return strconv.Atoi(s)
+	case TypeFloat:
+		return strconv.ParseFloat(s, 64)
+	case TypeBool:
+		if s == "" { // empty values are falsy
+			return false, nil
+		}
+		return strconv.ParseBool(s)
+	case TypeTime:
+		for _, layout := range TimeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, errors.New("unrecognised date format")
+	case TypeDuration:
+		return time.ParseDuration(s)
+	case TypeList:
+		return splitList(s), nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %d", typ)
+}
+
+// splitList splits a comma-separated value into its trimmed non-empty items
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Value returns the evaluated value of the document attribute key converted
+// to typ. Errors are of type *AttribError and carry the attribute's line number.
+func (doc *Document) Value(key string, typ AttribType) (interface{}, error) {
+	s, ok := doc.attribs[key]
+	if !ok {
+		return nil, &AttribError{Key: key, Type: typ, Err: ErrNoAttrib}
+	}
+	v, err := coerce(s, typ)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok { // drop the duplicate value from the message
+			err = ne.Err
+		}
+		return nil, &AttribError{Key: key, Line: doc.attribLine(key), Type: typ, Value: s, Err: err}
+	}
+	return v, nil
+}
+
+// Str returns the value of attribute key
+func (doc *Document) Str(key string) (string, error) {
+	v, err := doc.Value(key, TypeString)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// Int returns the value of attribute key as an int
+func (doc *Document) Int(key string) (int, error) {
+	v, err := doc.Value(key, TypeInt)
+	if err != nil {
+		return 0, err
+	}
+	return v.(int), nil
+}
+
+// Float returns the value of attribute key as a float64
+func (doc *Document) Float(key string) (float64, error) {
+	v, err := doc.Value(key, TypeFloat)
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// Bool returns the value of attribute key as a bool; an empty value is false
+func (doc *Document) Bool(key string) (bool, error) {
+	v, err := doc.Value(key, TypeBool)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// Time returns the value of attribute key as a time.Time parsed using the
+// first matching layout in TimeLayouts
+func (doc *Document) Time(key string) (time.Time, error) {
+	v, err := doc.Value(key, TypeTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// Duration returns the value of attribute key as a time.Duration, eg "1h30m"
+func (doc *Document) Duration(key string) (time.Duration, error) {
+	v, err := doc.Value(key, TypeDuration)
+	if err != nil {
+		return 0, err
+	}
+	return v.(time.Duration), nil
+}
+
+// Strings returns the value of attribute key split on commas, eg "a, b, c"
+func (doc *Document) Strings(key string) ([]string, error) {
+	v, err := doc.Value(key, TypeList)
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
@@ -0,0 +1,49 @@
+package mdson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+)
+
+const typedSrc = `.weight: 1
+.ratio: 0.5
+.draft: false
+.date: 12July2023
+.updated: 2023-07-20
+.timeout: 1h30m
+.tags: health, ecology ,
+.bad weight: heavy
+`
+
+func TestTypedAttribs(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(typedSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	w, err := doc.Int("weight")
+	tu.Equal(t, w, 1)
+	tu.Equal(t, err, nil)
+	r, _ := doc.Float("ratio")
+	tu.Equal(t, r, 0.5)
+	d, _ := doc.Bool("draft")
+	tu.Equal(t, d, false)
+	date, err := doc.Time("date")
+	tu.Equal(t, err, nil)
+	tu.Equal(t, date, time.Date(2023, time.July, 12, 0, 0, 0, 0, time.UTC))
+	updated, _ := doc.Time("updated")
+	tu.Equal(t, updated.After(date), true)
+	dur, _ := doc.Duration("timeout")
+	tu.Equal(t, dur, 90*time.Minute)
+	tags, _ := doc.Strings("tags")
+	tu.Equal(t, tags, []string{"health", "ecology"})
+
+	_, err = doc.Int("bad weight")
+	tu.Equal(t, err.Error(), "line 8: attribute 'bad weight': cannot convert 'heavy' to int: invalid syntax")
+	_, err = doc.Bool("missing")
+	tu.Equal(t, errors.Is(err, ErrNoAttrib), true)
+}
@@ -0,0 +1,91 @@
+package mdson
+
+import "strings"
+
+// Documents can be built or edited in code and then written out with Format
+// or any Transformer, eg
+//
+//	doc := NewDocument(nil)
+//	doc.SetAttrib("title", "Release notes")
+//	intro := NewBlock("Introduction").SetAttrib("status", "draft")
+//	intro.AddChild(NewTextLine("This release fixes {count} bugs."))
+//	doc.Root().AddChild(intro)
+//	Format(os.Stdout, doc)
+//
+// Blocks get their heading level when added to a parent, so trees can be
+// built in any order. Text and values are written as given: references in
+// them are expanded when the output is parsed again.
+
+// NewDocument returns an empty document; ctx defaults to a context with DefaultOptions()
+func NewDocument(ctx *Context) *Document {
+	if ctx == nil {
+		ctx = NewContext(DefaultOptions())
+	}
+	doc := newDocument(ctx)
+	doc.meta = &Metadata{Extra: make(map[string]string)}
+	return doc
+}
+
+// NewBlock returns a block; its heading level is set when it is added to a parent
+func NewBlock(name string) BlockNode {
+	return newBlock(name, 0)
+}
+
+// NewList returns a ~list; add items with AddChild(NewListItem(item))
+func NewList(name string) BlockNode {
+	return newList(name, 0)
+}
+
+// NewListItem returns a list item
+func NewListItem(item string) Node {
+	return newListItem(item)
+}
+
+// NewTextLine returns a line of text
+func NewTextLine(text string) Node {
+	return newTextLine(text)
+}
+
+// NewEmptyLine returns an empty line, eg to separate paragraphs
+func NewEmptyLine() Node {
+	return &ttEmpty{ttBase{kind: LtEmpty}}
+}
+
+// NewAttrib returns an attribute node as returned by BlockNode.Attribs
+func NewAttrib(key, value string) Node {
+	return newAttrib(key, value)
+}
+
+// SetAttrib sets the value of a document attribute and declares it in the
+// root block if it is not declared in any block. The declaration of key is
+// used if there is one, otherwise the first declaration in document order
+// whose key matches ignoring case; its key is kept as written.
+func (doc *Document) SetAttrib(key, value string) {
+	key = strings.TrimSpace(key)
+	att := doc.attribDecls[key]
+	if att == nil {
+		Inspect(doc.root, func(n Node) bool {
+			blk, ok := n.(BlockNode)
+			if !ok || att != nil {
+				return false
+			}
+			for _, a := range blk.attribNodes() {
+				if doc.attribDecls[a.key] == a && trimLower(a.key) == trimLower(key) {
+					att = a
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if att == nil {
+		doc.root.SetAttrib(key, value)
+		for _, a := range doc.root.attribNodes() {
+			if trimLower(a.key) == trimLower(key) {
+				att = a
+			}
+		}
+	}
+	att.value = value
+	doc.setAttrib(att)
+}
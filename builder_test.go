@@ -0,0 +1,158 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestBuildDocument(t *testing.T) {
+	doc := NewDocument(ctx)
+	doc.SetAttrib("title", "Release notes")
+	doc.SetAttrib("count", "3")
+	intro := NewBlock("Introduction").SetAttrib("status", "draft")
+	details := NewBlock("Details")
+	details.AddChild(NewTextLine("# not a heading"))
+	intro.AddChild(NewTextLine("This release fixes {count} bugs."))
+	intro.AddChild(NewEmptyLine())
+	intro.AddChild(details)
+	intro.AddChild(NewList("Fixed").AddChild(NewListItem("crash")).AddChild(NewListItem("leak")))
+	doc.Root().AddChild(intro)
+	tu.Equal(t, details.Level(), 2)
+
+	var sb strings.Builder
+	tu.Equal(t, Format(&sb, doc), nil)
+	want := []string{
+		".title: Release notes",
+		".count: 3",
+		"# Introduction",
+		".status: draft",
+		"This release fixes {count} bugs.",
+		"",
+		"~Fixed",
+		"- crash",
+		"- leak",
+		"## Details",
+		`\# not a heading`,
+		"",
+	}
+	tu.Equal(t, sb.String(), strings.Join(want, EOL))
+
+	// the output parses back into the same tree with references expanded
+	doc2, err := ctx.ParseFile("", strings.NewReader(sb.String()))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	v, _ := doc2.GetValue("introduction.status", TypeString)
+	tu.Equal(t, v, "draft")
+	v, _ = doc2.GetValue("introduction/fixed", TypeList)
+	tu.Equal(t, v, []string{"crash", "leak"})
+	n, _ := doc2.Get("introduction/details")
+	tu.Equal(t, n.(BlockNode).NthChild(0).Value(), "# not a heading")
+	tu.Equal(t, doc2.Root().NthChild(0).(BlockNode).NthChild(0).Value(), "This release fixes 3 bugs.")
+}
+
+func TestEditDocument(t *testing.T) {
+	src := "# A\n.x: 1\n.note: <<<\nline 1\nline 2\n>>>\ntext {x}\n# B\n## B1\n# C\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	root := doc.Root()
+	a := root.NthChild(0).(BlockNode)
+	a.SetAttrib("X", "2").SetAttrib("y", "new").RemoveAttrib("missing")
+	tu.Equal(t, len(a.Attribs()), 3)
+	root.MoveChild(2, 0) // C, A, B
+	b := root.NthChild(2).(BlockNode)
+	b1 := b.NthChild(0)
+	b.RemoveChild(0)
+	root.InsertChild(1, b1) // B1 becomes a top-level block
+	tu.Equal(t, b1.Level(), 1)
+	a.InsertChild(0, NewTextLine("{literal}"))
+
+	var sb strings.Builder
+	NewMDSonTransformer(DefaultTransformerConfig()).Transform(&sb, doc)
+	want := []string{
+		"# C",
+		"# B1",
+		"# A",
+		".x: 2",
+		".note: <<<",
+		"line 1",
+		"line 2",
+		">>>",
+		".y: new",
+		`\{literal\}`,
+		"text 1",
+		"# B",
+		"",
+	}
+	tu.Equal(t, sb.String(), strings.Join(want, EOL))
+
+	// document attributes keep the key they are declared with
+	doc.SetAttrib("X", "3")
+	tu.Equal(t, doc.attribs["x"], "3")
+	_, ok := doc.attribs["X"]
+	tu.Equal(t, ok, false)
+	v, _ := a.Attrib("x")
+	tu.Equal(t, v, "3")
+
+	// the first declaration in document order is updated
+	doc, err = ctx.ParseFile("", strings.NewReader("# A\n.Name: a\n# B\n.name: b\n# C\n.NAME: c\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	doc.SetAttrib("nAme", "new")
+	tu.Equal(t, doc.attribs, map[string]string{"Name": "new", "name": "b", "NAME": "c"})
+	doc.SetAttrib("name", "exact")
+	tu.Equal(t, doc.attribs["name"], "exact")
+	tu.Equal(t, doc.attribs["Name"], "new")
+}
+
+func TestFormatEscapes(t *testing.T) {
+	doc := NewDocument(nil)
+	doc.Root().AddChild(NewTextLine(`\#x`))
+	doc.Root().AddChild(NewTextLine(`\plain`))
+	doc.Root().AddChild(NewTextLine("#y"))
+	var sb strings.Builder
+	tu.Equal(t, Format(&sb, doc), nil)
+	parsed, err := ctx.ParseFile("", strings.NewReader(sb.String()))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	lines := []string{}
+	for _, n := range parsed.Root().Children() {
+		lines = append(lines, n.Value())
+	}
+	tu.Equal(t, lines, []string{`\#x`, `\plain`, "#y"})
+
+	// an empty value does not take the text line following it
+	empty, err := ctx.ParseFile("", strings.NewReader("# A\n<<x>>\n.empty:\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	sb.Reset()
+	tu.Equal(t, Format(&sb, empty), nil)
+	parsed, err = ctx.ParseFile("", strings.NewReader(sb.String()))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	a := parsed.Root().NthChild(0).(BlockNode)
+	v, ok := a.Attrib("empty")
+	tu.Equal(t, ok, true)
+	tu.Equal(t, v, "")
+	tu.Equal(t, len(a.Children()), 1)
+	tu.Equal(t, a.NthChild(0).Value(), "<<x>>")
+
+	doc.SetAttrib("code", "a\n>>> b")
+	sb.Reset()
+	err = Format(&sb, doc)
+	tu.Equal(t, err != nil && strings.Contains(err.Error(), "cannot contain '>>>'"), true)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/drgo/mdson"
+	"github.com/drgo/mdson/site"
+)
+
+var buildCmd = &command{
+	name:  "build",
+	short: "render a directory tree of .mdson files",
+	run:   runBuild,
+}
+
+var watchCmd = &command{
+	name:  "watch",
+	short: "build, then rebuild the outputs affected by each change to the sources",
+	run:   runWatch,
+}
+
+// buildFlags registers the flags of the build and watch commands and returns
+// a function that completes the site configuration once the flags are parsed
+func buildFlags(fs *flag.FlagSet) func() (site.Config, error) {
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mdson %s [flags] [source dir]\n", fs.Name())
+		fs.PrintDefaults()
+	}
+	var cf commonFlags
+	cf.register(fs)
+	cfg := site.Config{SourceDir: "."}
+	fs.StringVar(&cfg.OutputDir, "o", "public", "output `dir`")
+	fs.StringVar(&cfg.Format, "to", "md", "output `format`: "+strings.Join(mdson.DefaultFormats().Names(), ", ")+
+		"; options are read from "+site.ConfigFile)
+	fs.BoolVar(&cfg.Drafts, "drafts", false, "include drafts")
+	fs.IntVar(&cfg.Workers, "j", 0, "number of files parsed concurrently (default number of CPUs)")
+	return func() (site.Config, error) {
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return cfg, fmt.Errorf("too many arguments")
+		}
+		if fs.NArg() == 1 {
+			cfg.SourceDir = fs.Arg(0)
+		}
+		cfg.Options = cf.options()
+		return cfg, nil
+	}
+}
+
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	config := buildFlags(fs)
+	fs.Parse(args)
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	s, err := site.Build(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("built %d pages into %s\n", len(s.Pages), cfg.OutputDir)
+	return nil
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	config := buildFlags(fs)
+	interval := fs.Duration("poll", 0, "how often sources are checked for changes (default 500ms)")
+	fs.Parse(args)
+	cfg, err := config()
+	if err != nil {
+		return err
+	}
+	s, err := site.Load(cfg)
+	if s == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if err := s.Render(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	fmt.Printf("built %d pages into %s; watching %s for changes (press ctrl+c to exit)\n",
+		len(s.Pages), cfg.OutputDir, cfg.SourceDir)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s.Watch(ctx, *interval, report)
+	return nil
+}
+
+// report prints the outcome of a rebuild
+func report(r *site.Rebuild) {
+	fmt.Printf("%s %s changed: parsed %d, wrote %d, removed %d\n", time.Now().Format("15:04:05"),
+		strings.Join(r.Changed, ", "), len(r.Parsed), len(r.Written), len(r.Removed))
+	for _, out := range r.Written {
+		fmt.Println("  wrote", out)
+	}
+	for _, out := range r.Removed {
+		fmt.Println("  removed", out)
+	}
+	for _, w := range r.Warnings {
+		fmt.Fprintln(os.Stderr, w)
+	}
+	if r.Err != nil {
+		fmt.Fprintln(os.Stderr, r.Err)
+	}
+}
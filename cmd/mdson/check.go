@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/drgo/mdson"
+)
+
+var checkCmd = &command{
+	name:  "check",
+	short: "report errors and warnings in documents; exits with status 1 on errors",
+	run:   runCheck,
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mdson check [flags] [file ...]\n\nReads standard input if no file is given.")
+		fs.PrintDefaults()
+	}
+	var cf commonFlags
+	cf.register(fs)
+	schemaFile := fs.String("schema", "", "validate documents against the schema in `file`")
+	quiet := fs.Bool("q", false, "do not report warnings")
+	fs.Parse(args)
+	ctx := mdson.NewContext(cf.options())
+	var schema *mdson.Schema
+	if *schemaFile != "" {
+		var err error
+		if schema, err = ctx.ParseSchema(*schemaFile, nil); err != nil {
+			return err
+		}
+	}
+	names := inputs(fs.Args())
+	failed := 0
+	for _, name := range names {
+		var diags mdson.Diagnostics
+		doc, err := parse(ctx, name)
+		switch {
+		case err == nil:
+			diags = doc.Diagnostics()
+			if schema != nil {
+				diags = append(diags, mdson.Validate(doc, schema)...)
+			}
+		case !errors.As(err, &diags):
+			diags = nil
+			fmt.Fprintln(os.Stderr, err)
+			failed++
+			continue
+		}
+		diags.Sort()
+		for _, d := range diags {
+			if d.Severity == mdson.SevError || !*quiet {
+				fmt.Fprintln(os.Stderr, d)
+			}
+		}
+		if diags.HasErrors() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files have errors", failed, len(names))
+	}
+	return nil
+}
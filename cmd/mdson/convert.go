@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/drgo/mdson"
+)
+
+var convertCmd = &command{
+	name:  "convert",
+	short: "render documents in one of the output formats listed by -to",
+	run:   runConvert,
+}
+
+// formats returns the output formats with the options set in the
+// configuration file config, if any (see mdson.FormatMap.Load)
+func formats(ctx *mdson.Context, config string) (mdson.FormatMap, error) {
+	fm := mdson.DefaultFormats()
+	if config == "" {
+		return fm, nil
+	}
+	doc, err := ctx.ParseFile(config, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := fm.Load(doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", config, err)
+	}
+	return fm, nil
+}
+
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mdson convert [flags] [file ...]\n\nReads standard input if no file is given. Several files are only\naccepted for md and mdson output, which are joined.")
+		fs.PrintDefaults()
+	}
+	var cf commonFlags
+	cf.register(fs)
+	format := fs.String("to", "md", "output `format`: "+strings.Join(mdson.DefaultFormats().Names(), ", "))
+	out := fs.String("o", "", "output `file`; standard output if empty")
+	config := fs.String("config", "", "MDSon `file` setting the options of output formats")
+	fs.Parse(args)
+	ctx := mdson.NewContext(cf.options())
+	fm, err := formats(ctx, *config)
+	if err != nil {
+		return err
+	}
+	f, err := fm.Get(*format)
+	if err != nil {
+		return err
+	}
+	names := inputs(fs.Args())
+	if len(names) > 1 && !concatenable[f.Name] {
+		return fmt.Errorf("%s output holds a single document; convert one file at a time", f.Name)
+	}
+	// the output is only written once all inputs are parsed and rendered so
+	// that errors do not leave a truncated file behind
+	var buf bytes.Buffer
+	t := f.Transformer()
+	for _, name := range names {
+		doc, err := parse(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := t.Transform(&buf, doc); err != nil {
+			return fmt.Errorf("error rendering '%s': %s", name, err)
+		}
+	}
+	if *out == "" {
+		_, err = buf.WriteTo(os.Stdout)
+		return err
+	}
+	return os.WriteFile(*out, buf.Bytes(), 0o644)
+}
+
+// concatenable lists the formats whose outputs can be joined into one file
+var concatenable = map[string]bool{"md": true, "mdson": true}
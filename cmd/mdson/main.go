@@ -0,0 +1,112 @@
+// Command mdson processes MDSon documents.
+//
+// Usage:
+//
+//	mdson <command> [flags] [arguments]
+//
+// Run mdson help for the list of commands and mdson <command> -h for the
+// flags of a command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/drgo/core/ui"
+	"github.com/drgo/mdson"
+)
+
+// command is an mdson subcommand
+type command struct {
+	name  string
+	short string
+	run   func(args []string) error
+}
+
+// commands lists the subcommands in the order shown by help
+var commands []*command
+
+func main() {
+	commands = []*command{convertCmd, checkCmd, queryCmd, buildCmd, watchCmd, serveCmd}
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" {
+		usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "mdson:", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "mdson: unknown command '%s'\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: mdson <command> [flags] [arguments]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.short)
+	}
+}
+
+// commonFlags are accepted by all commands that parse documents
+type commonFlags struct {
+	debug   int
+	attribs attribFlag
+}
+
+func (cf *commonFlags) register(fs *flag.FlagSet) {
+	cf.attribs = attribFlag{}
+	fs.IntVar(&cf.debug, "debug", 0, "debug level")
+	fs.Var(cf.attribs, "set", "set attribute `key=value`, overriding the documents' value; can be repeated")
+}
+
+// options returns parsing options reflecting the flags
+func (cf *commonFlags) options() *mdson.Options {
+	opts := mdson.DefaultOptions().SetDebug(ui.Debug(cf.debug))
+	opts.Attribs = cf.attribs
+	return opts
+}
+
+// inputs returns the files named on the command line or "-" for standard
+// input if there are none
+func inputs(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
+
+// parse parses the file name or standard input if name is "-"
+func parse(ctx *mdson.Context, name string) (*mdson.Document, error) {
+	if name == "-" {
+		return ctx.ParseFile("<stdin>", os.Stdin)
+	}
+	return ctx.ParseFile(name, nil)
+}
+
+// attribFlag collects repeated -set key=value flags
+type attribFlag map[string]string
+
+func (af attribFlag) String() string {
+	pairs := []string{}
+	for k, v := range af {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (af attribFlag) Set(s string) error {
+	k, v, found := strings.Cut(s, "=")
+	if !found || strings.TrimSpace(k) == "" {
+		return fmt.Errorf("expected key=value, got '%s'", s)
+	}
+	af[strings.TrimSpace(k)] = v
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/drgo/mdson"
+)
+
+var queryCmd = &command{
+	name:  "query",
+	short: "print the value of an attribute or the contents of a block",
+	run:   runQuery,
+}
+
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), `usage: mdson query [flags] path [file]
+
+Prints the attributes, blocks and list items selected by path, eg
+  title                               a document attribute
+  job/document                        a block
+  job/document/sections list[0]       the first block of a block
+  job/inputfilenames[1]               the second item of a list
+  job/**.InputDir                     attributes of a block and its descendants
+Names are case-insensitive and may contain the wildcards * and ?.
+Reads standard input if no file is given.
+`)
+		fs.PrintDefaults()
+	}
+	var cf commonFlags
+	cf.register(fs)
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return fmt.Errorf("expected a path and at most one file")
+	}
+	doc, err := parse(mdson.NewContext(cf.options()), inputs(fs.Args()[1:])[0])
+	if err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	nodes, err := doc.Select(path)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		// document attributes include those that are not declared in the
+		// source, eg defaults
+		if v, err := doc.Str(path); err == nil {
+			fmt.Println(v)
+			return nil
+		}
+		return fmt.Errorf("'%s' not found", path)
+	}
+	for _, n := range nodes {
+		switch n.Kind() {
+		case mdson.LtAttrib, mdson.LtListItem:
+			fmt.Println(strings.TrimSpace(n.Value()))
+		default:
+			printNode(n)
+		}
+	}
+	return nil
+}
+
+// printNode prints n and its descendants as MDSon
+func printNode(n mdson.Node) {
+	switch n.Kind() {
+	case mdson.LtBlock:
+		fmt.Println(strings.Repeat("#", n.Level()) + " " + strings.TrimSpace(n.Value()))
+	case mdson.LtList:
+		fmt.Println("~" + n.Value())
+	case mdson.LtListItem:
+		fmt.Println("-" + n.Value())
+	default:
+		fmt.Println(n.Value())
+	}
+	if b, ok := n.(mdson.BlockNode); ok {
+		for _, c := range b.Children() {
+			printNode(c)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/drgo/mdson"
+	"github.com/drgo/mdson/preview"
+	"github.com/drgo/mdson/site"
+)
+
+var serveCmd = &command{
+	name:  "serve",
+	short: "preview .mdson files as HTML with live reload",
+	run:   runServe,
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: mdson serve [flags] [dir]\n\nPages are rendered with the html options of dir/"+site.ConfigFile+", if any.")
+		fs.PrintDefaults()
+	}
+	var cf commonFlags
+	cf.register(fs)
+	addr := fs.String("addr", "localhost:5500", "`address` to listen on; must be a loopback address")
+	interval := fs.Duration("poll", 0, "how often sources are checked for changes (default 500ms)")
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("too many arguments")
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+	host, _, err := net.SplitHostPort(*addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("refusing to serve on '%s': not a loopback address", host)
+	}
+	// render pages as build -to html does
+	config := filepath.Join(root, site.ConfigFile)
+	if _, err := os.Stat(config); err != nil {
+		config = ""
+	}
+	fm, err := formats(mdson.NewContext(cf.options()), config)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s := preview.New(preview.Config{Root: root, Options: cf.options(), Formats: fm, Interval: *interval})
+	go s.Run(ctx)
+	srv := &http.Server{Addr: *addr, Handler: s, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		// event streams never end, so close rather than wait for them
+		srv.Close()
+	}()
+	fmt.Printf("serving %s at http://%s/ (press ctrl+c to exit)\n", root, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package mdson
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decode stores the block at path (see Select), or the root if path is
+// empty, in the struct pointed to by v. Fields are matched to attributes,
+// ~lists and child blocks by the names and mdson tags used by
+// SchemaFromStruct, ignoring case: struct fields are filled from child
+// blocks, slices of structs from the child blocks of a list of blocks and
+// []string fields from lists or comma-separated values. Fields without a
+// matching attribute or block keep their values, so v can hold defaults.
+// The tag options required, oneof, min and max are enforced. Conversion
+// errors and values breaking the options are of type *AttribError.
+func (doc *Document) Decode(path string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Decode: %T is not a pointer to a struct", v)
+	}
+	blk := BlockNode(doc.root)
+	if path != "" {
+		n, err := doc.Get(path)
+		if err != nil {
+			return err
+		}
+		b, ok := n.(BlockNode)
+		if !ok || n.Kind() != LtBlock {
+			return fmt.Errorf("'%s' is not a block", path)
+		}
+		blk = b
+	}
+	return decodeBlock(blk, rv.Elem())
+}
+
+// decodeBlock stores blk in the struct rv
+func decodeBlock(blk BlockNode, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("mdson"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		name := f.Name
+		if tag[0] != "" {
+			name = tag[0]
+		}
+		fv := rv.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct && ft.Elem() != timeType {
+			child := childBlock(blk, name)
+			if child == nil {
+				continue
+			}
+			if fv.IsNil() {
+				fv.Set(reflect.New(ft.Elem()))
+			}
+			if err := decodeBlock(child, fv.Elem()); err != nil {
+				return err
+			}
+			continue
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != timeType:
+			if child := childBlock(blk, name); child != nil {
+				if err := decodeBlock(child, fv); err != nil {
+					return err
+				}
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			list := childBlock(blk, name)
+			if list == nil && !isArray(trimLower(name)) {
+				list = childBlock(blk, name+" List")
+			}
+			if list == nil {
+				continue
+			}
+			items := reflect.MakeSlice(ft, 0, len(list.Children()))
+			for _, c := range list.Children() {
+				if c.Kind() != LtBlock {
+					continue
+				}
+				item := reflect.New(ft.Elem()).Elem()
+				if err := decodeBlock(c.(BlockNode), item); err != nil {
+					return err
+				}
+				items = reflect.Append(items, item)
+			}
+			fv.Set(items)
+		default:
+			typ, err := attribTypeOf(ft)
+			if err != nil {
+				return fmt.Errorf("field %s.%s: %s", t.Name(), f.Name, err)
+			}
+			as := &AttribSchema{Name: name, Type: typ}
+			for _, opt := range tag[1:] {
+				if err := as.setOption(opt); err != nil {
+					return fmt.Errorf("field %s.%s: %s", t.Name(), f.Name, err)
+				}
+			}
+			if err := decodeValue(blk, as, fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeValue stores the attribute or ~list described by as declared in blk
+// in fv enforcing the options of as (required, oneof, min and max)
+func decodeValue(blk BlockNode, as *AttribSchema, fv reflect.Value) error {
+	name, typ := as.Name, as.Type
+	var (
+		s    string
+		line int
+		src  Node
+	)
+	for _, a := range blk.Attribs() {
+		if strings.EqualFold(name, strings.TrimSpace(a.Key())) {
+			src = a
+		}
+	}
+	if src == nil {
+		for _, c := range blk.Children() {
+			if c.Kind() == LtList && strings.EqualFold(name, strings.TrimSpace(c.Value())) {
+				src = c
+				break
+			}
+		}
+	}
+	if src == nil {
+		if as.Required {
+			return &AttribError{Key: name, Type: typ, Err: ErrNoAttrib}
+		}
+		return nil
+	}
+	line = src.LineNum()
+	var items []string
+	if src.Kind() == LtList {
+		items = []string{}
+		for _, c := range src.(BlockNode).Children() {
+			if c.Kind() == LtListItem {
+				items = append(items, strings.TrimSpace(c.Value()))
+			}
+		}
+		s = strings.Join(items, ", ")
+	} else {
+		s = src.Value()
+	}
+	var v interface{} = items
+	var err error
+	if items == nil || typ != TypeList { // list items may contain commas
+		v, err = coerce(s, typ)
+	}
+	if err == nil {
+		err = as.check(s, v)
+	}
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok { // drop the duplicate value from the message
+			err = ne.Err
+		}
+		return &AttribError{Key: name, Line: line, Type: typ, Value: strings.TrimSpace(s), Err: err}
+	}
+	fv.Set(reflect.ValueOf(v).Convert(fv.Type()))
+	return nil
+}
+
+// check returns an error if the value s, converted to v, breaks the oneof,
+// min or max options of as
+func (as *AttribSchema) check(s string, v interface{}) error {
+	values := []string{s}
+	if items, ok := v.([]string); ok {
+		if as.MinItems > 0 && len(items) < as.MinItems {
+			return fmt.Errorf("has %d items; at least %d required", len(items), as.MinItems)
+		}
+		if as.MaxItems > 0 && len(items) > as.MaxItems {
+			return fmt.Errorf("has %d items; at most %d allowed", len(items), as.MaxItems)
+		}
+		values = items
+	}
+	if len(as.Allowed) == 0 {
+		return nil
+	}
+outer:
+	for _, value := range values {
+		for _, a := range as.Allowed {
+			if strings.EqualFold(a, strings.TrimSpace(value)) {
+				continue outer
+			}
+		}
+		return fmt.Errorf("'%s' is not one of %s", strings.TrimSpace(value), strings.Join(as.Allowed, ", "))
+	}
+	return nil
+}
+
+// checkKeys returns an *AttribError for the first attribute, ~list or child
+// block of blk that matches no field of the struct type t (see decodeBlock)
+func checkKeys(blk BlockNode, t reflect.Type) error {
+	known := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("mdson"), ",")[0]
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		known[trimLower(name)] = true
+		known[trimLower(name+" List")] = true
+	}
+	var nodes []Node
+	for _, a := range blk.Attribs() {
+		nodes = append(nodes, a)
+	}
+	for _, c := range blk.Children() {
+		if c.Kind() == LtList || c.Kind() == LtBlock {
+			nodes = append(nodes, c)
+		}
+	}
+	for _, n := range nodes {
+		key := n.Key()
+		if n.Kind() != LtAttrib {
+			key = n.Value()
+		}
+		if !known[trimLower(key)] {
+			return &AttribError{Key: strings.TrimSpace(key), Line: n.LineNum(), Err: ErrUnknownAttrib}
+		}
+	}
+	return nil
+}
+
+// childBlock returns the child block of blk called name if any
+func childBlock(blk BlockNode, name string) BlockNode {
+	for _, c := range blk.Children() {
+		if c.Kind() == LtBlock && strings.EqualFold(name, strings.TrimSpace(c.Value())) {
+			return c.(BlockNode)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package mdson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+type decodeChild struct {
+	Name string
+	Age  int
+}
+
+type decodeFamily struct {
+	Name     string    `mdson:"family name"`
+	Income   float64   `mdson:"income"`
+	Since    time.Time `mdson:"since"`
+	Pets     []string
+	Tags     []string
+	Address  struct{ City string }
+	Children []decodeChild
+	Note     string
+}
+
+func TestDecode(t *testing.T) {
+	src := `# Family
+.family name: Smith
+.income: 1000.5
+.since: 2020-01-02
+.tags: a, b
+~ pets
+- cat
+- dog
+## Address
+.city: Halifax
+## Children List
+### Child
+.name: Ann
+.age: 7
+### Child
+.name: Bob
+.age: 5
+`
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.AllowDuplicatesInLists = true
+	doc, err := NewContext(opts).ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	f := decodeFamily{Note: "default"}
+	tu.Equal(t, doc.Decode("family", &f), nil)
+	tu.Equal(t, f.Name, "Smith")
+	tu.Equal(t, f.Income, 1000.5)
+	tu.Equal(t, f.Since, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+	tu.Equal(t, f.Pets, []string{"cat", "dog"})
+	tu.Equal(t, f.Tags, []string{"a", "b"})
+	tu.Equal(t, f.Address.City, "Halifax")
+	tu.Equal(t, f.Children, []decodeChild{{"Ann", 7}, {"Bob", 5}})
+	tu.Equal(t, f.Note, "default")
+
+	doc, err = ctx.ParseFile("", strings.NewReader(".age: old\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	var c decodeChild
+	err = doc.Decode("", &c)
+	var ae *AttribError
+	tu.Equal(t, errors.As(err, &ae), true)
+	if ae != nil {
+		tu.Equal(t, ae.Line, 1)
+	}
+	tu.Equal(t, doc.Decode("", c) != nil, true)
+
+	// tag options are enforced
+	var job struct {
+		Command string   `mdson:"command,required,oneof=run|check"`
+		Tags    []string `mdson:"tags,max=2"`
+	}
+	for src, want := range map[string]string{
+		".command: Run\n":                 "",
+		".tags: a\n":                      "attribute 'command': no such attribute",
+		".command: stop\n":                "'stop' is not one of run, check",
+		".command: run\n.tags: a, b, c\n": "has 3 items; at most 2 allowed",
+	} {
+		doc, err = ctx.ParseFile("", strings.NewReader(src))
+		tu.Equal(t, err, nil)
+		if err != nil {
+			continue
+		}
+		err = doc.Decode("", &job)
+		tu.Equal(t, err == nil, want == "")
+		if err != nil {
+			tu.Equal(t, strings.Contains(err.Error(), want), true)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package mdson
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Attributes can be supplied from outside a document. In increasing order of
+// precedence, an attribute's value comes from:
+//   - Options.DefaultsFile, an MDSon file whose attributes act as defaults
+//   - Options.Defaults
+//   - the document itself
+//   - Options.Attribs, eg values set on the command line
+//
+// In addition, {env.NAME} refers to the environment variable NAME provided
+// that NAME is listed in Options.Env.
+
+const envPrefix = "env."
+
+// loadDefaults sets the attributes supplied by Options.DefaultsFile and
+// Options.Defaults; it must be called before parsing the document so that
+// the document's own attributes take precedence
+func (doc *Document) loadDefaults() error {
+	if doc.ctx.DefaultsFile != "" {
+		// the defaults file is parsed with the same options except that it has no defaults itself
+		dctx := *doc.ctx
+		dctx.DefaultsFile = ""
+		dctx.Defaults = nil
+		defaults, err := dctx.ParseFile(doc.ctx.DefaultsFile, nil)
+		if err != nil {
+			return fmt.Errorf("error loading defaults file: %s", err)
+		}
+		doc.sources = append(doc.sources, doc.ctx.DefaultsFile)
+		for k, v := range defaults.attribs {
+			doc.attribs[k] = v
+		}
+	}
+	for k, v := range doc.ctx.Defaults {
+		doc.attribs[k] = v
+	}
+	return nil
+}
+
+// envAllowed reports whether Options.Env permits reading variable name; an
+// entry ending with * permits all variables starting with the rest of the entry
+func (ctx *Context) envAllowed(name string) bool {
+	for _, e := range ctx.Env {
+		if prefix, ok := strings.CutSuffix(e, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getEnvValue returns the value of env.NAME references or nil if the variable
+// is not set or not allowed
+func (doc *Document) getEnvValue(name string) *string {
+	if !doc.ctx.envAllowed(name) {
+		return nil
+	}
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,47 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+func TestExternalAttribs(t *testing.T) {
+	t.Setenv("MDSON_BUILD", "42")
+	t.Setenv("SECRET", "hidden")
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.DefaultsFile = "test/defaults.mdson"
+	opts.Defaults = map[string]string{"version": "2.0", "audience": "students"}
+	opts.Attribs = map[string]string{"edition": "instructor"}
+	opts.Env = []string{"MDSON_*"}
+	src := `.audience: instructors
+.edition: student
+v{version} for {audience} by {publisher}, {edition} edition, build {env.MDSON_BUILD}
+{env.SECRET ?? "no secret"}
+`
+	doc, err := NewContext(opts).ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tu.Equal(t, doc.root.NthChild(0).Value(), "v2.0 for instructors by Example Press, instructor edition, build 42")
+	tu.Equal(t, doc.root.NthChild(1).Value(), "no secret")
+}
+
+func TestSources(t *testing.T) {
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.DefaultsFile = "test/defaults.mdson"
+	doc, err := NewContext(opts).ParseFile("test/blocks.md", nil)
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tu.Equal(t, doc.Sources(), []string{"test/blocks.md", "test/defaults.mdson"})
+	doc, err = NewContext(opts).ParseFile("", strings.NewReader("text\n"))
+	tu.Equal(t, err, nil)
+	if err == nil {
+		tu.Equal(t, doc.Sources(), []string{"test/defaults.mdson"})
+	}
+}
@@ -0,0 +1,92 @@
+package mdson
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity classifies a Diagnostic
+type Severity int
+
+const (
+	SevError Severity = iota
+	SevWarning
+)
+
+func (s Severity) String() string {
+	if s == SevWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic describes a problem found at a given line of an MDSon source
+type Diagnostic struct {
+	FileName string
+	Line     int
+	Severity Severity
+	Msg      string
+}
+
+// Error formats the diagnostic as "file:line: msg" or "line n: msg" if the
+// file name is unknown; warnings are prefixed with "warning: ".
+func (d Diagnostic) Error() string {
+	pos := fmt.Sprintf("line %d", d.Line)
+	if d.FileName != "" {
+		pos = fmt.Sprintf("%s:%d", d.FileName, d.Line)
+	}
+	if d.Severity == SevWarning {
+		return pos + ": warning: " + d.Msg
+	}
+	return pos + ": " + d.Msg
+}
+
+// Diagnostics is a list of problems found while parsing or evaluating a
+// Document. It implements the error interface so that all problems found in
+// one parse can be returned as a single error.
+type Diagnostics []*Diagnostic
+
+func (dl *Diagnostics) add(fileName string, line int, sev Severity, msg string) {
+	*dl = append(*dl, &Diagnostic{FileName: fileName, Line: line, Severity: sev, Msg: msg})
+}
+
+// HasErrors reports whether the list contains at least one error (as opposed to warnings)
+func (dl Diagnostics) HasErrors() bool {
+	for _, d := range dl {
+		if d.Severity == SevError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort sorts the list by file name and line number keeping the order of
+// diagnostics reported for the same line
+func (dl Diagnostics) Sort() {
+	sort.SliceStable(dl, func(i, j int) bool {
+		if dl[i].FileName != dl[j].FileName {
+			return dl[i].FileName < dl[j].FileName
+		}
+		return dl[i].Line < dl[j].Line
+	})
+}
+
+func (dl Diagnostics) Error() string {
+	var sb strings.Builder
+	for i, d := range dl {
+		if i > 0 {
+			sb.WriteString(lineBreak)
+		}
+		sb.WriteString(d.Error())
+	}
+	return sb.String()
+}
+
+// Err returns the list as an error if it contains any errors; otherwise it returns nil
+func (dl Diagnostics) Err() error {
+	if !dl.HasErrors() {
+		return nil
+	}
+	return dl
+}
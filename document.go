@@ -1,16 +1,39 @@
 package mdson
 
 import (
+	"bytes"
+	"fmt"
 	"strings"
+	"time"
 )
 
 //TODO add Document interface
 // Document represents parsed and evaluated mdson Document
 // It implements fs.File to enable reading and seeking the evaluated text
+// rendered by a Transformer (see fs.go)
 type Document struct {
 	ctx *Context
+	// name of the source file if known
+	path string
+	// files read to build the document
+	sources []string
 	root        BlockNode 
 	attribs  map[string]string
+	// the declarations of attribs; used to position errors
+	attribDecls map[string]*ttAttrib
+	// problems found while parsing and evaluating the document
+	diags Diagnostics
+	// front matter
+	meta *Metadata
+	// state used by built-in functions
+	stats    docStats
+	counters map[string]int
+	// used by Read and Seek (see fs.go)
+	transformer Transformer
+	rendered    *bytes.Reader
+	// file info returned by Stat
+	name    string
+	modTime time.Time
 }
 
 func newDocument(ctx *Context) *Document{
@@ -19,6 +42,7 @@ func newDocument(ctx *Context) *Document{
 		root: newBlock("root",0),
 
 		attribs: make(map[string]string),
+		attribDecls: make(map[string]*ttAttrib),
 	}
 }
 
@@ -27,6 +51,41 @@ func (doc Document) Attribs() map[string]string {
 	return doc.attribs
 }
 
+// setAttrib makes a declared attribute available to the whole document
+func (doc *Document) setAttrib(att *ttAttrib) {
+	doc.attribs[att.key] = att.value
+	doc.attribDecls[att.key] = att
+}
+
+// Root returns the root block of the document; its children are the
+// top-level blocks, lists and text lines
+func (doc Document) Root() BlockNode {
+	return doc.root
+}
+
+// Path returns the name of the file the document was parsed from, if any
+func (doc Document) Path() string {
+	return doc.path
+}
+
+// Sources returns the files read to build the document: its source file
+// unless it was parsed from an io.Reader, and Options.DefaultsFile if set.
+// MDSon has no include directive, so a change to any other file cannot
+// affect the document.
+func (doc Document) Sources() []string {
+	return doc.sources
+}
+
+// Diagnostics returns all errors and warnings reported while parsing and
+// evaluating the document
+func (doc Document) Diagnostics() Diagnostics {
+	return doc.diags
+}
+
+func (doc *Document) addDiagnostic(line int, sev Severity, format string, args ...interface{}) {
+	doc.diags.add(doc.path, line, sev, fmt.Sprintf(format, args...))
+}
+
 func (doc Document) String() string {
 	var sb strings.Builder
 	sb.Grow(1024)
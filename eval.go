@@ -3,6 +3,7 @@ package mdson
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/drgo/booker/tu"
@@ -17,6 +18,11 @@ import (
 
 func (doc *Document) eval()  error{
 	tu.Assert(doc!=nil, "doc is nil in eval()")
+	// attributes set by the caller override those declared in the document
+	for k, v := range doc.ctx.Attribs {
+		doc.attribs[k] = v
+	}
+	doc.computeStats(doc.root)
 	if err:= doc.evalAllAttribs(doc.root); err!=nil {
 		return err 
 	}
@@ -31,9 +37,11 @@ func (doc *Document) eval()  error{
 	return nil
 }
 
-var reCurelyBraces = regexp.MustCompile(`{([^{}]*)}`)
 
 func (doc *Document) getAttribValue(att string) *string{
+	if strings.HasPrefix(att, envPrefix) {
+		return doc.getEnvValue(att[len(envPrefix):])
+	}
 	a, ok := doc.Attribs()[att]
 	if ok {
 		return &a 
@@ -42,7 +50,12 @@ func (doc *Document) getAttribValue(att string) *string{
 }
 
 
-func (doc *Document) evalAttribRefs(s string) string{
+// evalAttribRefs replaces each {reference} in s with its value. A reference
+// is either an attribute name (which may contain spaces) or an expression
+// (see expr.go). References that cannot be evaluated are replaced by an
+// <error: ...> marker and reported as warnings at line lnum.
+// \{ and \} produce literal braces and text in `code spans` is left as is.
+func (doc *Document) evalAttribRefs(s string, lnum int) string{
 	repl:= func (s string) string {
 		if len(s) <3 {
 			return fmt.Sprintf("<error: too short attribute '%s'>", s) 
@@ -54,46 +67,234 @@ func (doc *Document) evalAttribRefs(s string) string{
 		if expanded:=doc.getAttribValue(s); expanded != nil {
 			return *expanded 
 		}
-		return fmt.Sprintf("<error: no such attribute '%s'>", s) 
+		v, err := doc.evalExpr(s)
+		if err != nil {
+			doc.addDiagnostic(lnum, SevWarning, "{%s}: %s", s, err)
+			return fmt.Sprintf("<error: %s>", err) 
+		}
+		return toString(v)
 	}	
-	return reCurelyBraces.ReplaceAllStringFunc(s, repl)
+	return expandRefs(s, repl)
+}
+
+// expandRefs replaces each innermost {...} in s with repl({...}) except
+// in code spans and where the brace is escaped with a backslash
+func expandRefs(s string, repl func(string) string) string {
+	if !strings.ContainsAny(s, "{}\\`") { // hot path
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '{' || s[i+1] == '}') {
+				i++
+			}
+			sb.WriteByte(s[i])
+		case '`':
+			end := strings.IndexByte(s[i+1:], '`')
+			if end == -1 { // unclosed span
+				sb.WriteByte(c)
+				break
+			}
+			sb.WriteString(s[i : i+end+2])
+			i += end + 1
+		case '{':
+			end := strings.IndexAny(s[i+1:], "{}\\`")
+			if end == -1 || s[i+1+end] != '}' { // not a reference
+				sb.WriteByte(c)
+				break
+			}
+			sb.WriteString(repl(s[i : i+end+2]))
+			i += end + 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
 }
 
 func (doc *Document) evalLeaf(n Node)(Node, error) {
 	//TODO: guard against evaluating empty, error what else?
-	s:= doc.evalAttribRefs(n.Value())
+	if n.Kind() == LtCustom { // left to the plugin that claimed it
+		return n, nil
+	}
+	s:= doc.evalAttribRefs(n.Value(), n.LineNum())
 	doc.ctx.Log("**************** evalLeaf(): " + s)
 	n.SetValue(s)	
 	return n, nil
 }
 
+// evalBlock evaluates the children of n dropping those excluded by {if}
+// lines (see evalConditional()); blocks excluded by their .if attribute were
+// dropped by evalAllAttribs
 func (doc *Document) evalBlock(n BlockNode)(BlockNode, error) {
 	doc.ctx.Log("evalBlock() start:", n.Key())
-	count:= len(n.Children())
-	for i := 0; i < count; i++ {
-		switch c:= n.NthChild(i).(type){
-		case BlockNode:
-			_,err :=doc.evalBlock(c)  
-			if err!=nil {
-				return nil, err
+	// the state of the {if} lines of each block
+	conds := make(map[BlockNode]*condStack)
+	pre := func(c *Cursor) bool {
+		n := c.Node()
+		if parent := c.Parent(); parent != nil {
+			cs := conds[parent]
+			if cs == nil {
+				cs = &condStack{}
+				conds[parent] = cs
 			}
-			// n.UpdateChild(i, en)
-		//TODO: guard against evaluating errors etc
-		default:
-			if _, err :=doc.evalLeaf(c); err!=nil {
-				return nil, err 
-			}	
-		}		
+			if doc.evalConditional(n, cs) || !cs.active() {
+				c.Delete()
+				return false
+			}
+		}
+		if _, ok := n.(BlockNode); !ok {
+			//TODO: guard against evaluating errors etc
+			doc.evalLeaf(n)
+		}
+		return true
+	}
+	post := func(c *Cursor) bool {
+		if blk, ok := c.Node().(BlockNode); ok && conds[blk] != nil {
+			for _, open := range *conds[blk] {
+				doc.addDiagnostic(open.line, SevWarning, "{if} without a matching {end}")
+			}
+		}
+		return true
 	}
+	Apply(n, pre, post)
 	return n, nil  
 }
 
+// Conditional content: a block is only kept if its .if attribute, if any, is
+// true; lines between {if expr} and {end} lines (with an optional {else}) are
+// only kept if expr is true. Conditions are expressions (see expr.go) and can
+// use attributes set in Options.Attribs, eg
+//
+//	{if edition == "instructor"}
+//	answers...
+//	{else}
+//	questions...
+//	{end}
+var reDirective = regexp.MustCompile(`^\s*\{\s*(if\s+(.+?)|else|end)\s*\}\s*$`)
+
+// cond is the state of an open {if}
+type cond struct {
+	line int
+	// whether lines in the current branch are kept
+	active bool
+	// whether any branch was taken so far
+	taken bool
+	// whether the enclosing content is kept
+	outer bool
+}
+
+type condStack []cond
+
+func (cs condStack) active() bool {
+	return len(cs) == 0 || cs[len(cs)-1].active
+}
+
+// evalConditional updates conds if n is an {if}, {else} or {end} line and
+// reports whether it was one
+func (doc *Document) evalConditional(n Node, conds *condStack) bool {
+	if n.Kind() != LtTextLine {
+		return false
+	}
+	m := reDirective.FindStringSubmatch(n.Value())
+	if m == nil {
+		return false
+	}
+	switch {
+	case m[1] == "else":
+		if len(*conds) == 0 {
+			doc.addDiagnostic(n.LineNum(), SevWarning, "{else} without {if}")
+			break
+		}
+		top := &(*conds)[len(*conds)-1]
+		top.active = top.outer && !top.taken
+		top.taken = true
+	case m[1] == "end":
+		if len(*conds) == 0 {
+			doc.addDiagnostic(n.LineNum(), SevWarning, "{end} without {if}")
+			break
+		}
+		*conds = (*conds)[:len(*conds)-1]
+	default:
+		outer := conds.active()
+		// conditions in excluded content are not evaluated
+		on := outer && doc.condition(m[2], n.LineNum())
+		*conds = append(*conds, cond{line: n.LineNum(), active: on, taken: on, outer: outer})
+	}
+	return true
+}
+
+// included reports whether the .if attribute of a block, if any, is true
+func (doc *Document) included(n BlockNode) bool {
+	for _, a := range n.attribNodes() {
+		if trimLower(a.key) == "if" {
+			return doc.condition(a.value, a.LineNum())
+		}
+	}
+	return true
+}
+
+// condition evaluates expr; content whose condition cannot be evaluated is excluded
+func (doc *Document) condition(expr string, lnum int) bool {
+	v, err := doc.evalExpr(expr)
+	if err != nil {
+		doc.addDiagnostic(lnum, SevWarning, "condition '%s': %s; content excluded", expr, err)
+		return false
+	}
+	return isTrue(v)
+}
+
+// evalAllAttribs evaluates attribute values before the text referring to
+// them. Blocks are visited in document order: those excluded by their .if
+// attribute are dropped with their attributes, so that nothing can refer to
+// them; the declarations of the others are evaluated once and made available
+// to the whole document unless overridden (see eval()). The remaining values,
+// eg defaults, are evaluated in key order. Conditions (.if) are expressions
+// and are left as is.
 func (doc *Document) evalAllAttribs(n BlockNode) error {
-	for k,v := range doc.Attribs(){
-		s:= doc.evalAttribRefs(v)
-		if s!=v {
-			doc.Attribs()[k]=s 
-		}	
+	evaluated := make(map[string]bool)
+	Apply(n, func(c *Cursor) bool {
+		blk, ok := c.Node().(BlockNode)
+		if !ok {
+			return false
+		}
+		if c.Parent() != nil && !doc.included(blk) {
+			c.Delete()
+			return false
+		}
+		for _, a := range blk.attribNodes() {
+			if trimLower(a.key) == "if" {
+				continue
+			}
+			a.setValue(doc.evalAttribRefs(a.value, a.LineNum()))
+			if _, overridden := doc.ctx.Attribs[a.key]; !overridden {
+				doc.setAttrib(a)
+				evaluated[a.key] = true
+			}
+		}
+		return true
+	}, nil)
+	keys := make([]string, 0, len(doc.attribs))
+	for k := range doc.attribs {
+		if !evaluated[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc.attribs[k] = doc.evalAttribRefs(doc.attribs[k], doc.attribLine(k))
+	}
+	return nil
+}
+
+// attribLine returns the line where attribute key was declared or 0 if it was
+// not declared in the document
+func (doc *Document) attribLine(key string) int {
+	if decl := doc.attribDecls[key]; decl != nil {
+		return decl.LineNum()
 	}
-	return nil  
+	return 0
 }
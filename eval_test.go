@@ -1,22 +1,98 @@
 package mdson
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
 )
 
 
 
 
 func TestEval(t *testing.T) {
-	doc, err := ctx.ParseFile("", specs)
-	tu.Equal(t, err, nil)
-	if err != nil {
-		return
-	}
+	doc := parseSpecs(t)
 	tu.Equal(t, doc.attribs["date"], "12July2023")
 	tu.Equal(t, doc.attribs["today"], "Today is 12July2023")
 		
 	// t.Logf("\n\n%s\n", doc.root.Children()[0]) 
 }
+
+const editionSrc = `.edition: student
+# Exercise 1
+What is 2 + 2?
+{if edition == "instructor"}
+Answer: 4
+{else}
+Write your answer here.
+{end}
+# Answers
+.if: edition == "instructor"
+1. 4
+`
+
+func TestConditionalContent(t *testing.T) {
+	render := func(ctx *Context) string {
+		doc, err := ctx.ParseFile("", strings.NewReader(editionSrc))
+		tu.Equal(t, err, nil)
+		if err != nil {
+			return ""
+		}
+		var sb strings.Builder
+		NewMDTransformer(DefaultTransformerConfig()).Transform(&sb, doc)
+		return strings.ReplaceAll(sb.String(), EOL, "\n")
+	}
+	tu.Equal(t, render(ctx), "# Exercise 1\nWhat is 2 + 2?\nWrite your answer here.\n")
+
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.Attribs = map[string]string{"edition": "instructor"}
+	tu.Equal(t, render(NewContext(opts)), "# Exercise 1\nWhat is 2 + 2?\nAnswer: 4\n# Answers\n1. 4\n")
+}
+
+func TestExcludedAttribs(t *testing.T) {
+	src := ".secret: public\n# A\n.if: false\n.secret: leaked\n.hidden: leaked\n# B\nvalue {secret} {hidden}\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tu.Equal(t, doc.root.NthChild(0).Value(), "B")
+	tu.Equal(t, doc.root.NthChild(0).(BlockNode).NthChild(0).Value(), "value public <error: no such attribute 'hidden'>")
+	secret, err := doc.Str("secret")
+	tu.Equal(t, err, nil)
+	tu.Equal(t, secret, "public")
+	_, err = doc.Str("hidden")
+	tu.Equal(t, err != nil, true)
+}
+
+func TestEscapes(t *testing.T) {
+	src := `.name: mdson
+\# not a heading {name}
+\- not a list item
+\.not: an attribute
+\// not a comment
+JSON: \{"name": "{name}"\}
+code: ` + "`{\"name\": 1}`" + ` and {name}
+LaTeX: \\frac\{a\}\{b\}
+`
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	got := []string{}
+	for _, c := range doc.root.Children() {
+		got = append(got, c.Value())
+	}
+	tu.Equal(t, got, []string{
+		"# not a heading mdson",
+		"- not a list item",
+		".not: an attribute",
+		"// not a comment",
+		`JSON: {"name": "mdson"}`,
+		"code: `{\"name\": 1}` and mdson",
+		`LaTeX: \\frac{a}{b}`,
+	})
+	tu.Equal(t, len(doc.Diagnostics()), 0)
+}
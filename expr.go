@@ -0,0 +1,537 @@
+package mdson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expressions are evaluated inside {...} references, eg
+//
+//	{title | upper}
+//	{author ?? "Anonymous"}
+//	{chapter + ": " + title}
+//	{weight * 2 > 10}
+//
+// Operators by increasing precedence:
+//
+//	|                 filter, eg x | upper
+//	??                default when the left side is undefined or empty
+//	||  &&            logical or, and
+//	== != < <= > >=   comparison; numeric if both sides are numbers
+//	+ -               addition or, if either side is not a number, concatenation
+//	* / %             multiplication, division, remainder
+//	! -               logical not, negation
+//
+// Operands are numbers, "double-quoted" strings, true, false, parenthesised
+// expressions, attribute names and function calls (see funcs.go). Attribute
+// values that look like numbers or bools are treated as such. Only functions
+// have side effects, eg counter(), and expressions are bounded in length and
+// nesting depth.
+
+const (
+	maxExprLen   = 1024
+	maxExprDepth = 32
+)
+
+var errUndefined = errors.New("undefined")
+
+// exprError reports an attribute reference that cannot be evaluated
+type exprError struct {
+	msg string
+	err error
+}
+
+func (e *exprError) Error() string {
+	return e.msg
+}
+
+func (e *exprError) Unwrap() error {
+	return e.err
+}
+
+type tokenKind int
+
+const (
+	tkEOF tokenKind = iota
+	tkNumber
+	tkString
+	tkIdent
+	tkOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+// lexExpr splits an expression into tokens
+func lexExpr(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(s[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s'", s[i:j])
+			}
+			toks = append(toks, token{kind: tkNumber, text: s[i:j], num: f})
+			i = j
+		case r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+				sb.WriteByte(s[j])
+			}
+			if j == len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			toks = append(toks, token{kind: tkString, text: sb.String()})
+			i = j + 1
+		case r == '_' || unicode.IsLetter(r):
+			j := i + size
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				j += size
+			}
+			toks = append(toks, token{kind: tkIdent, text: s[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, o := range []string{"??", "||", "&&", "==", "!=", "<=", ">=",
+				"|", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character '%c'", r)
+			}
+			toks = append(toks, token{kind: tkOp, text: op})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tkEOF}), nil
+}
+
+// exprEval evaluates an expression while parsing it
+type exprEval struct {
+	doc   *Document
+	toks  []token
+	pos   int
+	depth int
+}
+
+func (doc *Document) evalExpr(s string) (interface{}, error) {
+	if len(s) > maxExprLen {
+		return nil, fmt.Errorf("expression longer than %d characters", maxExprLen)
+	}
+	toks, err := lexExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	e := &exprEval{doc: doc, toks: toks}
+	v, err := e.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if t := e.peek(); t.kind != tkEOF {
+		return nil, fmt.Errorf("unexpected '%s'", t.text)
+	}
+	return v, nil
+}
+
+func (e *exprEval) peek() token {
+	return e.toks[e.pos]
+}
+
+func (e *exprEval) next() token {
+	t := e.toks[e.pos]
+	if t.kind != tkEOF {
+		e.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators ops
+func (e *exprEval) accept(ops ...string) (string, bool) {
+	t := e.peek()
+	if t.kind != tkOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			e.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (e *exprEval) pipe() (interface{}, error) {
+	v, err := e.coalesce()
+	for {
+		if _, ok := e.accept("|"); !ok {
+			return v, err
+		}
+		t := e.next()
+		if t.kind != tkIdent {
+			return nil, errors.New("expected a filter name after '|'")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f, found := filters[t.text]; found {
+			v = f(v)
+			continue
+		}
+		f := e.doc.lookupFunc(t.text)
+		if f == nil {
+			return nil, fmt.Errorf("unknown filter '%s'", t.text)
+		}
+		if v, err = f(e.doc, v); err != nil {
+			return nil, fmt.Errorf("%s(): %s", t.text, err)
+		}
+	}
+}
+
+func (e *exprEval) coalesce() (interface{}, error) {
+	v, err := e.or()
+	for {
+		if _, ok := e.accept("??"); !ok {
+			return v, err
+		}
+		alt, altErr := e.or()
+		if err != nil && !errors.Is(err, errUndefined) {
+			return nil, err
+		}
+		if err != nil || toString(v) == "" {
+			v, err = alt, altErr
+		}
+	}
+}
+
+func (e *exprEval) or() (interface{}, error) {
+	v, err := e.and()
+	for err == nil {
+		if _, ok := e.accept("||"); !ok {
+			break
+		}
+		var r interface{}
+		if r, err = e.and(); err == nil {
+			v = isTrue(v) || isTrue(r)
+		}
+	}
+	return v, err
+}
+
+func (e *exprEval) and() (interface{}, error) {
+	v, err := e.cmp()
+	for err == nil {
+		if _, ok := e.accept("&&"); !ok {
+			break
+		}
+		var r interface{}
+		if r, err = e.cmp(); err == nil {
+			v = isTrue(v) && isTrue(r)
+		}
+	}
+	return v, err
+}
+
+func (e *exprEval) cmp() (interface{}, error) {
+	l, err := e.add()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := e.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return l, nil
+	}
+	r, err := e.add()
+	if err != nil {
+		return nil, err
+	}
+	var c int
+	lf, lnum := l.(float64)
+	rf, rnum := r.(float64)
+	switch {
+	case lnum && rnum:
+		c = compareFloats(lf, rf)
+	default:
+		c = strings.Compare(toString(l), toString(r))
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (e *exprEval) add() (interface{}, error) {
+	v, err := e.mul()
+	for err == nil {
+		op, ok := e.accept("+", "-")
+		if !ok {
+			break
+		}
+		var r interface{}
+		if r, err = e.mul(); err != nil {
+			break
+		}
+		lf, lnum := v.(float64)
+		rf, rnum := r.(float64)
+		switch {
+		case lnum && rnum && op == "+":
+			v = lf + rf
+		case lnum && rnum:
+			v = lf - rf
+		case op == "+":
+			v = toString(v) + toString(r)
+		default:
+			err = fmt.Errorf("cannot subtract '%s' from '%s'", toString(r), toString(v))
+		}
+	}
+	return v, err
+}
+
+func (e *exprEval) mul() (interface{}, error) {
+	v, err := e.unary()
+	for err == nil {
+		op, ok := e.accept("*", "/", "%")
+		if !ok {
+			break
+		}
+		var r interface{}
+		if r, err = e.unary(); err != nil {
+			break
+		}
+		lf, lnum := v.(float64)
+		rf, rnum := r.(float64)
+		if !lnum || !rnum {
+			return nil, fmt.Errorf("operator %s needs numbers, got '%s' and '%s'", op, toString(v), toString(r))
+		}
+		if rf == 0 && op != "*" {
+			return nil, errors.New("division by zero")
+		}
+		switch op {
+		case "*":
+			v = lf * rf
+		case "/":
+			v = lf / rf
+		default:
+			// the remainder is taken of the integer parts
+			if int64(rf) == 0 {
+				return nil, errors.New("division by zero")
+			}
+			v = float64(int64(lf) % int64(rf))
+		}
+	}
+	return v, err
+}
+
+func (e *exprEval) unary() (interface{}, error) {
+	op, ok := e.accept("!", "-")
+	if !ok {
+		return e.primary()
+	}
+	v, err := e.unary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "!" {
+		return !isTrue(v), nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate '%s'", toString(v))
+	}
+	return -f, nil
+}
+
+func (e *exprEval) primary() (interface{}, error) {
+	t := e.next()
+	switch t.kind {
+	case tkNumber:
+		return t.num, nil
+	case tkString:
+		return t.text, nil
+	case tkIdent:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if _, ok := e.accept("("); ok {
+			return e.call(t.text)
+		}
+		return e.lookup(t.text)
+	case tkOp:
+		if t.text == "(" {
+			if e.depth++; e.depth > maxExprDepth {
+				return nil, errors.New("expression nested too deeply")
+			}
+			v, err := e.pipe()
+			e.depth--
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := e.accept(")"); !ok {
+				return nil, errors.New("missing ')'")
+			}
+			return v, nil
+		}
+		return nil, fmt.Errorf("unexpected '%s'", t.text)
+	}
+	return nil, errors.New("unexpected end of expression")
+}
+
+// call evaluates the arguments of a function call up to the closing ) and calls the function
+func (e *exprEval) call(name string) (interface{}, error) {
+	f := e.doc.lookupFunc(name)
+	if f == nil {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+	if e.depth++; e.depth > maxExprDepth {
+		return nil, errors.New("expression nested too deeply")
+	}
+	defer func() { e.depth-- }()
+	var args []interface{}
+	if _, ok := e.accept(")"); !ok {
+		for {
+			v, err := e.pipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+			if _, ok := e.accept(")"); ok {
+				break
+			}
+			if _, ok := e.accept(","); !ok {
+				return nil, fmt.Errorf("expected ',' or ')' in call to %s()", name)
+			}
+		}
+	}
+	v, err := f(e.doc, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %s", name, err)
+	}
+	return v, nil
+}
+
+// lookup returns the value of an attribute as a number or a bool if it looks
+// like one, otherwise as a string. If there is no such attribute, it calls
+// the function of the same name if any.
+func (e *exprEval) lookup(name string) (interface{}, error) {
+	s := e.doc.getAttribValue(name)
+	if s == nil {
+		if f := e.doc.lookupFunc(name); f != nil {
+			v, err := f(e.doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s(): %s", name, err)
+			}
+			return v, nil
+		}
+		return nil, &exprError{msg: fmt.Sprintf("no such attribute '%s'", name), err: errUndefined}
+	}
+	return typedValue(*s), nil
+}
+
+func typedValue(s string) interface{} {
+	t := strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(t, 64); err == nil {
+		return f
+	}
+	switch strings.ToLower(t) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
+
+func isTrue(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != "" && !strings.EqualFold(v, "false")
+	}
+	return false
+}
+
+// toString formats an expression value for output
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// filters transform the value on the left of |
+var filters = map[string]func(interface{}) interface{}{
+	"upper": func(v interface{}) interface{} { return strings.ToUpper(toString(v)) },
+	"lower": func(v interface{}) interface{} { return strings.ToLower(toString(v)) },
+	"title": func(v interface{}) interface{} { return titleCase(toString(v)) },
+	"trim":  func(v interface{}) interface{} { return strings.TrimSpace(toString(v)) },
+	"len":   func(v interface{}) interface{} { return float64(utf8.RuneCountInString(toString(v))) },
+}
+
+// titleCase upper-cases the first letter of each word
+func titleCase(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
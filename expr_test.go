@@ -0,0 +1,50 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+const exprSrc = `.title: my great book
+.weight: 4
+.draft: false
+.empty:
+.prop with spaces: spaced
+`
+
+func TestExpressions(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(exprSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"{title}", "my great book"},
+		{"{prop with spaces}", "spaced"},
+		{"{title | upper}", "MY GREAT BOOK"},
+		{"{title | title}", "My Great Book"},
+		{`{author ?? "Anonymous"}`, "Anonymous"},
+		{`{empty ?? "none"}`, "none"},
+		{`{title ?? "none"}`, "my great book"},
+		{`{"v" + weight + ".0"}`, "v4.0"},
+		{"{weight * 2 + 1}", "9"},
+		{"{(weight + 2) / 4}", "1.5"},
+		{"{weight > 3 && !draft}", "true"},
+		{`{title == "my great book"}`, "true"},
+		{"{weight % 3}", "1"},
+		{"a {weight} b {weight - 1}", "a 4 b 3"},
+		{"{author}", "<error: no such attribute 'author'>"},
+		{"{weight / 0}", "<error: division by zero>"},
+		{"{weight % 0.5}", "<error: division by zero>"},
+		{"{title | shout}", "<error: unknown filter 'shout'>"},
+	}
+	for _, tt := range tests {
+		tu.Equal(t, doc.evalAttribRefs(tt.in, 10), tt.want)
+	}
+	tu.Equal(t, len(doc.Diagnostics()), 4)
+	tu.Equal(t, doc.Diagnostics()[0].Error(), "line 10: warning: {author}: no such attribute 'author'")
+}
@@ -0,0 +1,133 @@
+package mdson
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// An OutputFormat is a format documents can be rendered to
+type OutputFormat struct {
+	// eg html
+	Name string
+	// extension of output files including the dot, eg .html
+	Ext string
+	// options passed to New; see FormatMap.Load
+	Config TransformerConfig
+	// returns a Transformer using cfg
+	New func(cfg TransformerConfig) Transformer
+}
+
+// Transformer returns a Transformer using the format's options
+func (f *OutputFormat) Transformer() Transformer {
+	return f.New(f.Config)
+}
+
+// FormatMap maps lower-case format names to formats. Applications add their
+// own formats to the map returned by DefaultFormats.
+type FormatMap map[string]*OutputFormat
+
+// formatAliases maps alternative names to format names
+var formatAliases = map[string]string{"tex": "latex"}
+
+// DefaultFormats returns the built-in formats: md, html, mom, latex (also
+// called tex), json and mdson. The map is new and can be changed freely.
+func DefaultFormats() FormatMap {
+	cfg := DefaultTransformerConfig()
+	fm := FormatMap{}
+	fm.Register(&OutputFormat{Name: "md", Ext: ".md", Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewMDTransformer(cfg) }})
+	fm.Register(&OutputFormat{Name: "html", Ext: ".html", Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewHTMLTransformer(cfg) }})
+	fm.Register(&OutputFormat{Name: "mom", Ext: ".mom", Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewMomTransformer(cfg) }})
+	fm.Register(&OutputFormat{Name: "latex", Ext: ".tex", Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewLaTeXTransformer(cfg) }})
+	fm.Register(&OutputFormat{Name: "json", Ext: ".json", Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewJSONTransformer(cfg) }})
+	fm.Register(&OutputFormat{Name: "mdson", Ext: SourceExt, Config: cfg,
+		New: func(cfg TransformerConfig) Transformer { return NewMDSonTransformer(cfg) }})
+	return fm
+}
+
+// Register adds f to fm replacing any format with the same name
+func (fm FormatMap) Register(f *OutputFormat) {
+	fm[strings.ToLower(f.Name)] = f
+}
+
+// Get returns the format called name
+func (fm FormatMap) Get(name string) (*OutputFormat, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := formatAliases[key]; ok && fm[key] == nil {
+		key = alias
+	}
+	if f := fm[key]; f != nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported output format '%s'", name)
+}
+
+// New returns a Transformer for the format called name using its options
+func (fm FormatMap) New(name string) (Transformer, error) {
+	f, err := fm.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.Transformer(), nil
+}
+
+// Names returns the sorted names of the formats in fm
+func (fm FormatMap) Names() []string {
+	names := make([]string, 0, len(fm))
+	for name := range fm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a copy of fm whose formats can be configured without
+// changing those of fm
+func (fm FormatMap) Clone() FormatMap {
+	clone := make(FormatMap, len(fm))
+	for name, f := range fm {
+		c := *f
+		clone[name] = &c
+	}
+	return clone
+}
+
+// Load sets the options of formats from the top-level blocks of doc named
+// after them, eg a config.mdson file containing
+//
+//	# html
+//	.font: Georgia
+//	.number headings: true
+//	# latex
+//	.page size: a4
+//	.font size: 11
+//	.font package: lmodern
+//
+// Options not set in doc keep their values and other content is ignored.
+// Headings that name no format in fm are errors, as are unknown options and
+// invalid values, which are reported as *AttribError.
+func (fm FormatMap) Load(doc *Document) error {
+	for _, n := range doc.root.Children() {
+		if n.Kind() != LtBlock {
+			continue
+		}
+		f, err := fm.Get(n.Value())
+		if err != nil {
+			return fmt.Errorf("line %d: %s", n.LineNum(), err)
+		}
+		cfg := reflect.ValueOf(&f.Config).Elem()
+		if err := checkKeys(n.(BlockNode), cfg.Type()); err != nil {
+			return err
+		}
+		if err := decodeBlock(n.(BlockNode), cfg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
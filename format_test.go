@@ -0,0 +1,86 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestFormats(t *testing.T) {
+	fm := DefaultFormats()
+	tu.Equal(t, fm.Names(), []string{"html", "json", "latex", "md", "mdson", "mom"})
+	f, err := fm.Get("TeX")
+	tu.Equal(t, err, nil)
+	if err == nil {
+		tu.Equal(t, f.Name, "latex")
+		tu.Equal(t, f.Ext, ".tex")
+	}
+	_, err = fm.New("pdf")
+	tu.Equal(t, err != nil, true)
+
+	config := `.taxonomies: tags
+# md
+.line ending: lf
+.number headings: true
+# latex
+.page size: a4
+.font size: 11
+.font package: lmodern
+`
+	cfgDoc, err := ctx.ParseFile("", strings.NewReader(config))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	loaded := fm.Clone()
+	tu.Equal(t, loaded.Load(cfgDoc), nil)
+	tu.Equal(t, fm["md"].Config.NumberHeadings, false)
+	tu.Equal(t, loaded["md"].Config.NumberHeadings, true)
+	tu.Equal(t, loaded["latex"].Config.FontSize, 11)
+	tu.Equal(t, loaded["md"].Config.TabWidth, 2)
+
+	doc, err := ctx.ParseFile("", strings.NewReader("# A\na\n## B\n## C\n# D\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	render := func(name string) string {
+		var sb strings.Builder
+		tr, err := loaded.New(name)
+		tu.Equal(t, err, nil)
+		if err == nil {
+			tu.Equal(t, tr.Transform(&sb, doc), nil)
+		}
+		return sb.String()
+	}
+	tu.Equal(t, render("md"), "# 1 A\na\n## 1.1 B\n## 1.2 C\n# 2 D\n")
+	tex := render("latex")
+	tu.Equal(t, strings.HasPrefix(tex, `\documentclass[11pt,a4paper]{article}`+EOL+`\usepackage[utf8]{inputenc}`+EOL+`\usepackage{lmodern}`+EOL), true)
+
+	loaded["latex"].Config.PageSize = `a4}\input{x`
+	tu.Equal(t, loaded["latex"].Transformer().Transform(&strings.Builder{}, doc) != nil, true)
+	loaded["mom"].Config.Font = "T\n.so x"
+	tu.Equal(t, loaded["mom"].Transformer().Transform(&strings.Builder{}, doc) != nil, true)
+
+	for _, bad := range []string{"# html\n.font size: large\n", "# md\n.line ending: cr\n", "# html\n.fontsize: 11\n"} {
+		cfgDoc, err = ctx.ParseFile("", strings.NewReader(bad))
+		tu.Equal(t, err, nil)
+		if err == nil {
+			_, ok := fm.Clone().Load(cfgDoc).(*AttribError)
+			tu.Equal(t, ok, true)
+		}
+	}
+	cfgDoc, err = ctx.ParseFile("", strings.NewReader("# html\n.font: Georgia\n# htlm\n.font: x\n"))
+	tu.Equal(t, err, nil)
+	if err == nil {
+		err = fm.Clone().Load(cfgDoc)
+		tu.Equal(t, err != nil && err.Error() == "line 3: unsupported output format 'htlm'", true)
+	}
+	cfgDoc, err = ctx.ParseFile("", strings.NewReader("# html\n.fontsize: 11\n"))
+	tu.Equal(t, err, nil)
+	if err == nil {
+		err = fm.Clone().Load(cfgDoc)
+		tu.Equal(t, err != nil && err.Error() == "line 2: attribute 'fontsize': unknown attribute", true)
+	}
+}
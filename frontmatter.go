@@ -0,0 +1,200 @@
+package mdson
+
+import (
+	"strings"
+	"time"
+)
+
+// Metadata holds the front matter of a document: the attributes and ~lists
+// declared before its first heading, eg
+//
+//	.title: Environmental health
+//	.date: 17July2023
+//	.draft: true
+//	.tags: health, ecology
+//	~Authors
+//	- Salah Mahmud
+//	- Second author
+//
+// List-valued fields can be given either as comma-separated attribute values
+// or as ~lists. Plain Markdown files can provide the same fields as YAML (---)
+// or TOML (+++) front matter if Options.FrontMatter is set.
+type Metadata struct {
+	Type        string
+	Title       string
+	Subtitle    string
+	Description string
+	Authors     []string
+	Tags        []string
+	Date        time.Time
+	Updated     time.Time
+	// drafts are not built unless requested
+	Draft bool
+	// used to order documents; lower weights come first
+	Weight int
+	// number of items per page in generated listings; 0 means no pagination
+	PaginateBy int
+	// other front matter attributes and lists (joined by ", ") by lower-case name
+	Extra map[string]string
+}
+
+// Metadata returns the front matter of the document
+func (doc Document) Metadata() *Metadata {
+	return doc.meta
+}
+
+// buildMetadata fills doc.meta from the evaluated root attributes and lists
+func (doc *Document) buildMetadata() {
+	m := &Metadata{Extra: make(map[string]string)}
+	for _, a := range doc.root.attribNodes() {
+		doc.setMetadata(m, a, a.key, a.value, nil)
+	}
+	for _, c := range doc.root.Children() {
+		if c.Kind() != LtList {
+			continue
+		}
+		items := []string{}
+		for _, item := range c.(BlockNode).Children() {
+			if s := strings.TrimSpace(item.Value()); s != "" {
+				items = append(items, s)
+			}
+		}
+		doc.setMetadata(m, c, strings.TrimSuffix(strings.TrimSpace(c.Value()), ":"), strings.Join(items, ", "), items)
+	}
+	doc.meta = m
+}
+
+// setMetadata sets the field called key; items holds the items of a ~list
+func (doc *Document) setMetadata(m *Metadata, n Node, key, value string, items []string) {
+	if items == nil {
+		items = splitList(value)
+	}
+	key = trimLower(key)
+	var err error
+	var v interface{}
+	switch key {
+	case "type":
+		m.Type = value
+	case "title":
+		m.Title = value
+	case "subtitle":
+		m.Subtitle = value
+	case "description":
+		m.Description = value
+	case "authors", "author":
+		m.Authors = items
+	case "tags":
+		m.Tags = items
+	case "date", "updated":
+		if v, err = coerce(value, TypeTime); err == nil {
+			if key == "date" {
+				m.Date = v.(time.Time)
+			} else {
+				m.Updated = v.(time.Time)
+			}
+		}
+	case "draft":
+		if v, err = coerce(value, TypeBool); err == nil {
+			m.Draft = v.(bool)
+		}
+	case "weight", "paginate_by":
+		if v, err = coerce(value, TypeInt); err == nil {
+			if key == "weight" {
+				m.Weight = v.(int)
+			} else {
+				m.PaginateBy = v.(int)
+			}
+		}
+	default:
+		m.Extra[key] = value
+	}
+	if err != nil { // the field is left unset; see Validate for enforcing types
+		doc.addDiagnostic(n.LineNum(), SevWarning, "front matter: '%s' is not a valid value for '%s'; ignored", value, key)
+	}
+}
+
+// parseFrontMatter reads YAML (---) or TOML (+++) front matter at the start of
+// a plain Markdown file into root attributes and lists. Only flat key-value
+// pairs, [a, b] arrays and, in YAML, lists of "- item" lines are supported.
+// Files with front matter are treated as Markdown: list items outside ~lists
+// are kept as text.
+func (p *Parser) parseFrontMatter() {
+	if !p.readLine() {
+		return
+	}
+	delim := strings.TrimSpace(p.line)
+	if delim != "---" && delim != "+++" {
+		p.unreadLine()
+		return
+	}
+	p.markdown = true
+	sep := ":"
+	if delim == "+++" {
+		sep = "="
+	}
+	start := p.lineNum
+	// a YAML key with no value may be followed by list items
+	var list *ttList
+	pendingKey, pendingLine := "", 0
+	flush := func() {
+		if pendingKey != "" && list == nil {
+			p.addFrontMatterAttrib(pendingKey, "", pendingLine)
+		}
+		pendingKey, list = "", nil
+	}
+	for p.readLine() {
+		trimmed := strings.TrimSpace(p.line)
+		switch {
+		case trimmed == delim || (delim == "---" && trimmed == "..."):
+			flush()
+			return
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case pendingKey != "" && strings.HasPrefix(trimmed, "-"):
+			if list == nil {
+				list = newList(pendingKey, 0)
+				list.SetLineNum(pendingLine)
+				p.doc.root.AddChild(list)
+			}
+			item := newListItem(unquote(strings.TrimSpace(trimmed[1:])))
+			item.SetLineNum(p.lineNum)
+			list.AddChild(item)
+			continue
+		}
+		flush()
+		key, value, found := strings.Cut(p.line, sep)
+		if !found || strings.TrimSpace(key) == "" || key != strings.TrimLeft(key, " \t") {
+			p.syntaxError(p.lineNum, "unsupported front matter line")
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case value == "" && delim == "---":
+			pendingKey, pendingLine = key, p.lineNum
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			items := []string{}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				items = append(items, unquote(strings.TrimSpace(item)))
+			}
+			p.addFrontMatterAttrib(key, strings.Join(items, ", "), p.lineNum)
+		default:
+			p.addFrontMatterAttrib(key, unquote(value), p.lineNum)
+		}
+	}
+	p.syntaxError(start, "front matter not closed with '%s'", delim)
+}
+
+func (p *Parser) addFrontMatterAttrib(key, value string, lnum int) {
+	att := newAttrib(key, value)
+	att.SetLineNum(lnum)
+	p.doc.root.addAttrib(att)
+	p.doc.setAttrib(att)
+}
+
+// unquote removes matching single or double quotes around s
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+func TestMetadata(t *testing.T) {
+	src := `.title: Environmental health
+.date: 17July2023
+.draft: true
+.weight: 3
+.tags: health, ecology
+.agents: Salmonella
+~Authors
+- Salah Mahmud
+- Second author
+# Summary
+.title: not front matter
+`
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	m := doc.Metadata()
+	tu.Equal(t, m.Title, "Environmental health")
+	tu.Equal(t, m.Date, time.Date(2023, time.July, 17, 0, 0, 0, 0, time.UTC))
+	tu.Equal(t, m.Draft, true)
+	tu.Equal(t, m.Weight, 3)
+	tu.Equal(t, m.Tags, []string{"health", "ecology"})
+	tu.Equal(t, m.Authors, []string{"Salah Mahmud", "Second author"})
+	tu.Equal(t, m.Extra, map[string]string{"agents": "Salmonella"})
+
+	// invalid values are reported as warnings and leave the field unset
+	doc, err = ctx.ParseFile("", strings.NewReader(".weight: heavy\n.date: July 2023\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tu.Equal(t, doc.Metadata().Weight, 0)
+	tu.Equal(t, doc.Metadata().Date.IsZero(), true)
+	diags := doc.Diagnostics()
+	tu.Equal(t, len(diags), 2)
+	if len(diags) > 0 {
+		tu.Equal(t, diags[0].Severity, SevWarning)
+		tu.Equal(t, diags[0].Msg, "front matter: 'heavy' is not a valid value for 'weight'; ignored")
+	}
+}
+
+func TestYAMLFrontMatter(t *testing.T) {
+	src := `---
+title: "Environmental health"
+date: 2023-07-17
+tags: [health, 'ecology']
+authors:
+  - Salah Mahmud
+  - Second author
+draft: false
+---
+# Summary
+- a Markdown bullet
+`
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.FrontMatter = true
+	doc, err := NewContext(opts).ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	m := doc.Metadata()
+	tu.Equal(t, m.Title, "Environmental health")
+	tu.Equal(t, m.Date.Day(), 17)
+	tu.Equal(t, m.Tags, []string{"health", "ecology"})
+	tu.Equal(t, m.Authors, []string{"Salah Mahmud", "Second author"})
+	summary := doc.root.NthChild(1).(BlockNode)
+	tu.Equal(t, summary.NthChild(0).Value(), "- a Markdown bullet")
+
+	// without front matter the option changes nothing
+	doc, err = NewContext(opts).ParseFile("", strings.NewReader(".title: plain\n"))
+	tu.Equal(t, err, nil)
+	if err == nil {
+		tu.Equal(t, doc.Metadata().Title, "plain")
+	}
+}
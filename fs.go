@@ -0,0 +1,277 @@
+package mdson
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	_ fs.File       = &Document{}
+	_ io.ReadSeeker = &Document{}
+)
+
+// SetTransformer sets the Transformer used to render the document for Read
+// and Seek; MDTransformer is used if none is set
+func (doc *Document) SetTransformer(t Transformer) {
+	doc.transformer = t
+	doc.rendered = nil
+}
+
+// render renders the document unless it is already rendered
+func (doc *Document) render() error {
+	if doc.rendered != nil {
+		return nil
+	}
+	t := doc.transformer
+	if t == nil {
+		t = NewMDTransformer(DefaultTransformerConfig())
+	}
+	var buf bytes.Buffer
+	if err := t.Transform(&buf, doc); err != nil {
+		return err
+	}
+	doc.rendered = bytes.NewReader(buf.Bytes())
+	return nil
+}
+
+// Read reads the rendered document
+func (doc *Document) Read(p []byte) (int, error) {
+	if err := doc.render(); err != nil {
+		return 0, err
+	}
+	return doc.rendered.Read(p)
+}
+
+// Seek sets the offset for the next Read in the rendered document
+func (doc *Document) Seek(offset int64, whence int) (int64, error) {
+	if err := doc.render(); err != nil {
+		return 0, err
+	}
+	return doc.rendered.Seek(offset, whence)
+}
+
+// Stat describes the rendered document as a read-only file named after its
+// source; documents not read from a file are called document.mdson
+func (doc *Document) Stat() (fs.FileInfo, error) {
+	if err := doc.render(); err != nil {
+		return nil, err
+	}
+	name := doc.name
+	if name == "" && doc.path != "" {
+		name = filepath.Base(doc.path)
+	}
+	if name == "" {
+		name = "document" + SourceExt
+	}
+	return fileInfo{name: name, size: doc.rendered.Size(), modTime: doc.modTime}, nil
+}
+
+// Close discards the rendered document so that reading it again renders it
+// anew, eg after editing it
+func (doc *Document) Close() error {
+	doc.rendered = nil
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) Mode() fs.FileMode  { return 0o444 }
+func (fi fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fileInfo) IsDir() bool        { return false }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+// SourceExt is the extension of MDSon sources
+const SourceExt = ".mdson"
+
+// FS presents a file system of MDSon sources as their rendered output:
+// opening a.html parses a.mdson and renders it with the Transformer
+// registered for .html. Directory listings show each source under its name
+// with DefaultExt. Other files are served as they are, so an FS can be
+// passed to http.FileServer (via http.FS) or template.ParseFS.
+type FS struct {
+	src fs.FS
+	ctx *Context
+	// transformers by output extension, eg ".html"
+	transformers map[string]func() Transformer
+	// extension of the sources in directory listings
+	DefaultExt string
+}
+
+var _ fs.ReadDirFS = &FS{}
+
+// NewFS returns an FS rendering the sources in src; .md files are rendered
+// by MDTransformer and .html files by HTMLTransformer unless changed with Register
+func NewFS(src fs.FS, ctx *Context) *FS {
+	if ctx == nil {
+		ctx = NewContext(DefaultOptions())
+	}
+	f := &FS{src: src, ctx: ctx, transformers: make(map[string]func() Transformer), DefaultExt: ".html"}
+	formats := DefaultFormats()
+	f.RegisterFormat(formats["md"])
+	f.RegisterFormat(formats["html"])
+	return f
+}
+
+// RegisterFormat renders files with the extension of format using its
+// Transformer and options, eg those of DefaultFormats()["latex"] after Load
+func (f *FS) RegisterFormat(format *OutputFormat) {
+	f.Register(format.Ext, format.Transformer)
+}
+
+// Register sets the function returning the Transformer used for files with
+// the extension ext, eg ".tex"; newT is called once per file opened
+func (f *FS) Register(ext string, newT func() Transformer) {
+	f.transformers[ext] = newT
+}
+
+// source returns the name of the source rendered as name or "" if name is
+// not a rendered file
+func (f *FS) source(name string) string {
+	ext := path.Ext(name)
+	if _, ok := f.transformers[ext]; !ok {
+		return ""
+	}
+	src := strings.TrimSuffix(name, ext) + SourceExt
+	if fi, err := fs.Stat(f.src, src); err != nil || fi.IsDir() {
+		return ""
+	}
+	return src
+}
+
+// Open opens name. Sources that fail to parse return a *fs.PathError
+// wrapping their Diagnostics.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	src := f.source(name)
+	if src == "" {
+		file, err := f.src.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := file.(fs.ReadDirFile); ok {
+			return &dir{ReadDirFile: d, fsys: f, name: name}, nil
+		}
+		return file, nil
+	}
+	doc, err := f.parse(name, src)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return doc, nil
+}
+
+// parse parses the source src rendered as name
+func (f *FS) parse(name, src string) (*Document, error) {
+	file, err := f.src.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := f.ctx.ParseFile(src, file)
+	if err != nil {
+		return nil, err
+	}
+	doc.SetTransformer(f.transformers[path.Ext(name)]())
+	doc.name = path.Base(name)
+	doc.modTime = fi.ModTime()
+	return doc, nil
+}
+
+// ReadDir reads the directory name showing sources under their rendered name
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.src, name)
+	if err != nil {
+		return nil, err
+	}
+	return f.mapEntries(name, entries), nil
+}
+
+// mapEntries renames the source entries of directory name and sorts the
+// entries. Files with the name of a rendered source are left out since Open
+// returns the rendered source in their place.
+func (f *FS) mapEntries(name string, entries []fs.DirEntry) []fs.DirEntry {
+	rendered := make(map[string]bool)
+	for _, e := range entries {
+		if !e.IsDir() && path.Ext(e.Name()) == SourceExt {
+			rendered[strings.TrimSuffix(e.Name(), SourceExt)+f.DefaultExt] = true
+		}
+	}
+	mapped := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		switch {
+		case !e.IsDir() && path.Ext(e.Name()) == SourceExt:
+			out := strings.TrimSuffix(e.Name(), SourceExt) + f.DefaultExt
+			mapped = append(mapped, renderedEntry{fsys: f, path: path.Join(name, out)})
+		case !rendered[e.Name()]:
+			mapped = append(mapped, e)
+		}
+	}
+	sort.Slice(mapped, func(i, j int) bool { return mapped[i].Name() < mapped[j].Name() })
+	return mapped
+}
+
+// renderedEntry is the directory entry of a rendered source
+type renderedEntry struct {
+	fsys *FS
+	path string
+}
+
+func (e renderedEntry) Name() string      { return path.Base(e.path) }
+func (e renderedEntry) IsDir() bool       { return false }
+func (e renderedEntry) Type() fs.FileMode { return 0 }
+
+// Info renders the source to find the size of the output
+func (e renderedEntry) Info() (fs.FileInfo, error) {
+	return fs.Stat(e.fsys, e.path)
+}
+
+// dir is an open directory whose entries are renamed by its FS
+type dir struct {
+	fs.ReadDirFile
+	fsys    *FS
+	name    string
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fsys.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.read = entries, true
+	}
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
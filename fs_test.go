@@ -0,0 +1,91 @@
+package mdson
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestDocumentFile(t *testing.T) {
+	doc, err := ctx.ParseFile("test/intro.mdson", strings.NewReader("# Intro\nhello\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	b, err := io.ReadAll(doc)
+	tu.Equal(t, err, nil)
+	tu.Equal(t, string(b), "# Intro"+EOL+"hello"+EOL)
+	pos, err := doc.Seek(2, io.SeekStart)
+	tu.Equal(t, pos, int64(2))
+	b, _ = io.ReadAll(doc)
+	tu.Equal(t, string(b), "Intro"+EOL+"hello"+EOL)
+	fi, err := doc.Stat()
+	tu.Equal(t, err, nil)
+	tu.Equal(t, fi.Name(), "intro.mdson")
+	tu.Equal(t, fi.Size(), int64(16))
+
+	// closing discards the rendered text so that edits are seen
+	doc.Close()
+	doc.Root().NthChild(0).(BlockNode).AddChild(NewTextLine("edited"))
+	doc.SetTransformer(NewMDSonTransformer(DefaultTransformerConfig()))
+	b, _ = io.ReadAll(doc)
+	tu.Equal(t, string(b), "# Intro"+EOL+"hello"+EOL+"edited"+EOL)
+
+	doc, err = ctx.ParseFile("", strings.NewReader("text\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	fi, err = doc.Stat()
+	tu.Equal(t, err, nil)
+	tu.Equal(t, fi.Name(), "document.mdson")
+}
+
+func TestFS(t *testing.T) {
+	mod := time.Date(2023, 7, 12, 0, 0, 0, 0, time.UTC)
+	src := fstest.MapFS{
+		"index.mdson":     {Data: []byte("# Home\nwelcome\n"), ModTime: mod},
+		"docs/a.mdson":    {Data: []byte(".title: A\n# A\ntext\n"), ModTime: mod},
+		"docs/a.html":     {Data: []byte("stale output")},
+		"docs/logo.svg":   {Data: []byte("<svg/>")},
+		"docs/bad.mdson":  {Data: []byte("##bad\n")},
+		"style/site.css":  {Data: []byte("body{}")},
+		"style/notes.txt": {Data: []byte("notes")},
+	}
+	fsys := NewFS(src, ctx)
+	b, err := fs.ReadFile(fsys, "docs/a.html")
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "<h1>A</h1>"), true)
+	b, err = fs.ReadFile(fsys, "docs/a.md")
+	tu.Equal(t, string(b), "# A"+EOL+"text"+EOL)
+	b, err = fs.ReadFile(fsys, "docs/logo.svg")
+	tu.Equal(t, string(b), "<svg/>")
+	fi, err := fs.Stat(fsys, "index.html")
+	tu.Equal(t, err, nil)
+	tu.Equal(t, fi.ModTime(), mod)
+
+	entries, err := fs.ReadDir(fsys, "docs")
+	tu.Equal(t, err, nil)
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	tu.Equal(t, names, []string{"a.html", "bad.html", "logo.svg"})
+
+	_, err = fsys.Open("docs/bad.html")
+	var diags Diagnostics
+	tu.Equal(t, errors.As(err, &diags), true)
+	_, err = fsys.Open("docs/missing.html")
+	tu.Equal(t, errors.Is(err, fs.ErrNotExist), true)
+
+	delete(src, "docs/bad.mdson")
+	if err := fstest.TestFS(fsys, "index.html", "docs/a.html", "docs/logo.svg", "style/site.css"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,108 @@
+package mdson
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Func is a function that can be called in references, eg {today("2006-01-02")}
+// or {counter("figure")}. Arguments and results are strings, float64 numbers
+// or bools. Functions that take no arguments can be called without
+// parentheses, eg {words}, unless an attribute has the same name. A function
+// can also be used as a filter, eg {title | slug} calls slug(title).
+type Func func(doc *Document, args ...interface{}) (interface{}, error)
+
+// FuncMap maps names to functions; see Options.Funcs
+type FuncMap map[string]Func
+
+// DefaultDateLayout is used by today() and now() when no layout is given
+const DefaultDateLayout = "2January2006"
+
+// timeNow is replaced in tests
+var timeNow = time.Now
+
+var builtinFuncs = FuncMap{
+	// today([layout]) returns the current date
+	"today": func(doc *Document, args ...interface{}) (interface{}, error) {
+		return formatTime(timeNow(), DefaultDateLayout, args)
+	},
+	// now([layout]) returns the current date and time
+	"now": func(doc *Document, args ...interface{}) (interface{}, error) {
+		return formatTime(timeNow(), time.RFC3339, args)
+	},
+	// words() returns the number of words in the document's text
+	"words": func(doc *Document, args ...interface{}) (interface{}, error) {
+		return float64(doc.stats.words), nil
+	},
+	// blocks() returns the number of blocks in the document excluding the root
+	"blocks": func(doc *Document, args ...interface{}) (interface{}, error) {
+		return float64(doc.stats.blocks), nil
+	},
+	// path() returns the path of the source file
+	"path": func(doc *Document, args ...interface{}) (interface{}, error) {
+		return doc.path, nil
+	},
+	// file() returns the name of the source file without its directory
+	"file": func(doc *Document, args ...interface{}) (interface{}, error) {
+		if doc.path == "" {
+			return "", nil
+		}
+		return filepath.Base(doc.path), nil
+	},
+	// counter([name]) increments the named counter and returns its new value
+	"counter": func(doc *Document, args ...interface{}) (interface{}, error) {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("counter() takes at most one argument")
+		}
+		name := ""
+		if len(args) == 1 {
+			name = toString(args[0])
+		}
+		if doc.counters == nil {
+			doc.counters = make(map[string]int)
+		}
+		doc.counters[name]++
+		return float64(doc.counters[name]), nil
+	},
+}
+
+func formatTime(t time.Time, layout string, args []interface{}) (interface{}, error) {
+	switch len(args) {
+	case 0:
+	case 1:
+		layout = toString(args[0])
+	default:
+		return nil, fmt.Errorf("expected at most one layout argument, got %d", len(args))
+	}
+	return t.Format(layout), nil
+}
+
+// lookupFunc returns the function called name from Options.Funcs or the built-ins
+func (doc *Document) lookupFunc(name string) Func {
+	if f, ok := doc.ctx.Funcs[name]; ok {
+		return f
+	}
+	return builtinFuncs[name]
+}
+
+// docStats holds counts computed before evaluation
+type docStats struct {
+	words  int
+	blocks int
+}
+
+func (doc *Document) computeStats(n BlockNode) {
+	Inspect(n, func(c Node) bool {
+		switch c.Kind() {
+		case LtBlock:
+			if c != n {
+				doc.stats.blocks++
+			}
+		case LtTextLine, LtListItem:
+			doc.stats.words += len(strings.Fields(c.Value()))
+		}
+		return true
+	})
+}
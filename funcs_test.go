@@ -0,0 +1,51 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+func TestBuiltinFuncs(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2023, time.July, 12, 10, 30, 0, 0, time.UTC) }
+	defer func() { timeNow = time.Now }()
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.Funcs = FuncMap{
+		"slug": func(doc *Document, args ...interface{}) (interface{}, error) {
+			return strings.ReplaceAll(strings.ToLower(toString(args[0])), " ", "-"), nil
+		},
+	}
+	src := `.title: Heart Failure
+.NumBlocks: {blocks}
+# One
+Date: {today} or {today("2006-01-02")}
+Figure {counter("fig")}, Figure {counter("fig")}, Table {counter("table")}
+## Two
+{words} words in {file} at {title | slug}
+`
+	doc, err := NewContext(opts).ParseFile("test/x.mdson", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	one := doc.root.NthChild(0).(BlockNode)
+	tu.Equal(t, doc.attribs["NumBlocks"], "2")
+	tu.Equal(t, one.NthChild(0).Value(), "Date: 12July2023 or 2023-07-12")
+	tu.Equal(t, one.NthChild(1).Value(), "Figure 1, Figure 2, Table 1")
+	tu.Equal(t, one.NthChild(2).(BlockNode).NthChild(0).Value(), "18 words in x.mdson at heart-failure")
+	tu.Equal(t, doc.evalAttribRefs("{nosuch(1)}", 1), "<error: unknown function 'nosuch'>")
+
+	// attribute values are evaluated once, in document order
+	doc, err = NewContext(opts).ParseFile("", strings.NewReader(".fig: {counter}\n.tab: {counter}\n{counter}\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tu.Equal(t, doc.attribs["fig"], "1")
+	tu.Equal(t, doc.attribs["tab"], "2")
+	tu.Equal(t, doc.root.NthChild(0).Value(), "3")
+	tu.Equal(t, doc.root.Attribs()[1].Value(), "2")
+}
@@ -17,17 +17,43 @@ import (
 //Options holds parsing options
 type Options struct {
 	Debug ui.Debug
+	// initial size of the read buffer; lines longer than that are still read
 	BufferCap int
+	// maximum length of a source line in bytes; 0 means no limit
+	MaxLineLength int
 	// style of md list generated when list style is not specified
 	// ol=ordered, ul=unordered 
 	DefaultListStyle string 
+	// permit sibling blocks with the same name inside blocks whose name ends
+	// with " list" (eg ## Children List)
+	AllowDuplicatesInLists bool
+	// attributes that override those declared in documents, eg set from the
+	// command line to choose which conditional content is kept
+	Attribs map[string]string
+	// default attributes; overridden by those declared in documents
+	Defaults map[string]string
+	// MDSon file whose attributes are used as defaults; Defaults take precedence
+	DefaultsFile string
+	// environment variables that can be referred to as {env.NAME}; an entry
+	// ending with * allows all variables with that prefix, eg "MDSON_*"
+	Env []string
+	// functions that can be called in references in addition to the
+	// built-in ones (see funcs.go); they take precedence over built-ins
+	Funcs FuncMap
+	// accept YAML (---) or TOML (+++) front matter at the start of a source
+	// as found in plain Markdown files (see parseFrontMatter)
+	FrontMatter bool
+	// plugins claiming lines with their own prefixes; see AddPlugin
+	Plugins []*Plugin
 }
 
+const defaultBufferCap = 1024 * 10
+
 //DefaultOptions returns reasonable default for parsing
 func DefaultOptions() *Options {
 	return &Options{
 		Debug: ui.DebugUpdates,
-		BufferCap: 1024 * 10,
+		BufferCap: defaultBufferCap,
 		DefaultListStyle: "ol",
 	}
 }
@@ -35,7 +61,7 @@ func DefaultOptions() *Options {
 
 func (po Options ) String() string {
 	return fmt.Sprintf(
-	"Settings: Debug: %s | Buffer Capacity %d\n", po.Debug, po.BufferCap)
+	"Settings: Debug: %s | Buffer Capacity %d | Max Line Length %d\n", po.Debug, po.BufferCap, po.MaxLineLength)
 }
 //SetDebug sets verbosity level
 func (po *Options) SetDebug(d ui.Debug) *Options {
@@ -2,6 +2,7 @@ package mdson
 
 import (
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -11,12 +12,19 @@ import (
 type mom struct {
 	printer
 	TransformerConfig 
+	numbers *headingNumbers
+}
+
+// NewMomTransformer returns a Transformer that produces groff with mom macros
+func NewMomTransformer(cfg TransformerConfig) Transformer {
+	return &mom{TransformerConfig: cfg}
 }
 
 func newMom(w io.Writer, ctx *Context) mom {
 	m:= mom{
 		TransformerConfig : DefaultTransformerConfig(),
 		printer:  printer{w: w},
+		numbers: &headingNumbers{},
 	}
 	return m
 }
@@ -27,32 +35,45 @@ func (m mom) printNode(n Node) {
 //- print general preamble 
 //- use root attribs to create doc-specific preamble
 //- output nodes 
-	switch n := n.(type) {
-	case *ttBlock:
-		if n.Level() > 0 { // donot print root's title
-			m.println(strings.Repeat("#", n.Level()) + " " + n.Value())
-		}
-		m.Indent = strings.Repeat(" ", n.Level()* m.TabWidth)
-		for _, n := range n.Children() {
-			m.printNode(n)
+	Inspect(n, func(n Node) bool {
+		switch n := n.(type) {
+		case *ttBlock:
+			if n.Level() > 0 { // donot print root's title
+				m.println(strings.Repeat("#", n.Level()) + " " + m.numbers.next(n.Level(), m.NumberHeadings) + n.Value())
+			}
+			m.Indent = strings.Repeat(" ", n.Level()*m.TabWidth)
+		case *ttList:
+			m.println(n.Value())
+			m.Indent = strings.Repeat(" ", n.Level()*m.TabWidth)
+		case *ttListItem:
+			m.print(m.Indent + m.ListMaker)
+			m.println(n.Value())
+		case Custom:
+			m.custom(n)
+		default:
+			m.println(n.Value())
 		}
-	case *ttList:
-		m.println(n.Value())
-		m.Indent = strings.Repeat(" ", n.Level()* m.TabWidth)
-		for _, n := range n.Children() {
-			m.printNode(n)
-		}
-	case *ttListItem:
-		m.print(m.Indent+ m.ListMaker)
-		m.println(n.Value())
-	default:
-		m.println(n.Value())
-	}
+		return true
+	})
 }
 
 func (m *mom) Transform(w io.Writer, d *Document) error  {
+	if err := m.checkNames(); err != nil {
+		return err
+	}
+	m.printer = printer{w: w, format: "mom", plugins: d.plugins(), eol: m.lineEnding(), hookErr: new(error)}
+	m.numbers = &headingNumbers{}
+	if m.PageSize != "" {
+		m.println(".PAPER " + strings.ToUpper(m.PageSize))
+	}
+	if m.Font != "" {
+		m.println(".FAMILY " + m.Font)
+	}
+	if m.FontSize > 0 {
+		m.println(".PT_SIZE " + strconv.Itoa(m.FontSize))
+	}
 	m.printNode(d.root)
-	return	nil
+	return	m.renderErr()
 }
 
 
@@ -36,7 +36,11 @@ type Parser struct {
 	node     Node
 	nextNode Node
 	err      error
-	scanner  *bufio.Scanner
+	reader   *bufio.Reader
+	// if true, readLine returns the current line again
+	unread bool
+	// set for plain Markdown sources (see parseFrontMatter)
+	markdown bool
 }
 
 var errEOF = errors.New("end of file")
@@ -44,13 +48,15 @@ var errEOF = errors.New("end of file")
 // NewParser returns an initialized MDsonParser
 // FIXME: no need to expose since parser's funcs are not exposed
 func NewParser(ctx *Context, r io.Reader) *Parser {
+	bufSize := ctx.BufferCap
+	if bufSize <= 0 {
+		bufSize = defaultBufferCap
+	}
 	p := &Parser{
-		ctx:     ctx,
-		scanner: bufio.NewScanner(r),
-		doc:     newDocument(ctx),
+		ctx:    ctx,
+		reader: bufio.NewReaderSize(r, bufSize),
+		doc:    newDocument(ctx),
 	}
-	buf := make([]byte, ctx.BufferCap)
-	p.scanner.Buffer(buf, ctx.BufferCap)
 	return p
 }
 
@@ -60,6 +66,7 @@ func NewParser(ctx *Context, r io.Reader) *Parser {
 // TODO: add filename field
 func (ctx *Context) ParseFile(fileName string, r io.Reader) (*Document, error) {
 	// ctx.Log("inside mdson.ParseFile: parsing ", fileName, r)
+	var sources []string
 	if r == nil {
 		f, err := os.Open(fileName)
 		if err != nil {
@@ -67,18 +74,28 @@ func (ctx *Context) ParseFile(fileName string, r io.Reader) (*Document, error) {
 		}
 		defer f.Close()
 		r = f
+		sources = append(sources, fileName)
 	}
 	p := NewParser(ctx, r)
+	p.doc.path = fileName
+	p.doc.sources = sources
+	if err := p.doc.loadDefaults(); err != nil {
+		return nil, err
+	}
 	err := p.parse()
 	ctx.Log("inside mdson.ParseFile: parsing ", fileName, err)
 	if err != nil {
-		return throw(fmt.Errorf("error parsing file '%s': %s", fileName, err))
+		return nil, err
 	}
 	err = p.doc.eval()
 	if err != nil {
 		return throw(fmt.Errorf("error parsing file '%s': %s", fileName, err))
 	}
-	// p.doc.path= fileName
+	p.doc.buildMetadata()
+	p.doc.diags.Sort()
+	if err := p.doc.diags.Err(); err != nil {
+		return nil, err
+	}
 	// ctx.Log("exiting mdson.ParseFile", err, p.doc)
 
 	return p.doc, nil
@@ -92,28 +109,53 @@ func (p *Parser) Err() error {
 	return p.err
 }
 
-// Parse parses an MDson source
-// FIXME: validate block name uniqueness
+// Parse parses an MDson source. Syntax errors do not stop the parser; they are
+// collected in the document's diagnostics and returned together as a single
+// Diagnostics error. Read errors end parsing immediately.
 func (p *Parser) parse() error {
-	for p.parseBlock(p.doc.root) {
-		p.doc.root.AddChild(p.node)
+	if p.ctx.FrontMatter {
+		p.parseFrontMatter()
 	}
+	p.parseBlock(p.doc.root)
 	if p.Err() != nil {
-		return p.Err()
+		p.doc.addDiagnostic(p.lineNum+1, SevError, "%s", p.Err())
+		return p.doc.diags.Err()
 	}
-	return nil
+	p.doc.validate()
+	p.doc.diags.Sort()
+	return p.doc.diags.Err()
+}
+
+// syntaxError records a syntax error at line lnum and returns a node holding it
+func (p *Parser) syntaxError(lnum int, format string, args ...interface{}) *ttSyntaxError {
+	p.doc.addDiagnostic(lnum, SevError, format, args...)
+	se := newSyntaxError(fmt.Sprintf(format, args...))
+	se.SetLineNum(lnum)
+	return se
 }
 
 // read the next line and only returns non-comment lines or nil if EOF
 func (p *Parser) getNextNode() Node {
 	//TODO: verify error propagation is working
 	for p.readLine() {
+		if pl := pluginFor(p.ctx.Plugins, p.line); pl != nil {
+			return p.parseDirective(pl)
+		}
 		n := p.parseLine(p.line)
 		switch n.Kind() {
 		case LtComment:
 			continue
 		case LtEOF:
 			return nil
+		case LtSyntaxError:
+			return p.syntaxError(p.lineNum, "%s", n.(*ttSyntaxError).err)
+		case LtAttrib:
+			lnum := p.lineNum
+			if err := p.readRawValue(n.(*ttAttrib)); err != nil {
+				return err
+			}
+			n.SetLineNum(lnum)
+			return n
 		}
 		n.SetLineNum(p.lineNum)
 		return n
@@ -121,6 +163,77 @@ func (p *Parser) getNextNode() Node {
 	return nil
 }
 
+// readRawValue reads the rest of a multi-line attribute value, if any. The
+// value starts with << or <<< either after the colon or on the next line and
+// ends with >> or >>> respectively, eg
+//
+//	.abstract:
+//	<<< first line
+//	second line >>>
+//
+// Line breaks are kept in <<< >>> values; in << >> values, lines are trimmed
+// and joined by a single space. References in the value are expanded as usual.
+func (p *Parser) readRawValue(att *ttAttrib) *ttSyntaxError {
+	if att.value == "" { // the value may start on the next line
+		if !p.readLine() {
+			return nil
+		}
+		if !strings.HasPrefix(strings.TrimSpace(p.line), "<<") {
+			p.unreadLine()
+			return nil
+		}
+		att.value = strings.TrimSpace(p.line)
+	}
+	if !strings.HasPrefix(att.value, "<<") {
+		return nil
+	}
+	start, end, sep := "<<", ">>", " "
+	preserve := strings.HasPrefix(att.value, "<<<")
+	if preserve {
+		start, end, sep = "<<<", ">>>", "\n"
+	}
+	lnum := p.lineNum
+	// a space separating the value from the markers is not part of the value
+	text := strings.TrimPrefix(att.value[len(start):], " ")
+	var lines []string
+	for {
+		if i := strings.Index(text, end); i != -1 {
+			lines = append(lines, strings.TrimSuffix(text[:i], " "))
+			if strings.TrimSpace(text[i+len(end):]) != "" {
+				return p.syntaxError(p.lineNum, "unexpected text after '%s'", end)
+			}
+			break
+		}
+		lines = append(lines, text)
+		if !p.readLine() {
+			if p.Err() != nil { // a read error, reported by parse()
+				return nil
+			}
+			return p.syntaxError(lnum, "multi-line value of '%s' not closed with '%s'", att.key, end)
+		}
+		text = p.line
+	}
+	if preserve {
+		// markers on their own lines do not add line breaks
+		if strings.TrimSpace(lines[0]) == "" {
+			lines = lines[1:]
+		}
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+	} else {
+		folded := lines[:0]
+		for _, l := range lines {
+			if l = strings.TrimSpace(l); l != "" {
+				folded = append(folded, l)
+			}
+		}
+		lines = folded
+	}
+	att.value = strings.Join(lines, sep)
+	return nil
+}
+
 func (p *Parser) advance() bool {
 	p.ctx.Log("in advance(): node=", p.node, "nextNode=", p.nextNode)
 	if p.nextNode != nil { //if we already peeked, use that node
@@ -157,49 +270,58 @@ func (p *Parser) setError(err error) bool {
 	return false
 }
 
-var ln = 0
-
-// parseBlock return values: false+nil=EOF, false+!nil=error,true+ nil continue
+// parseBlock adds to parent all nodes until a heading of the same or a lower
+// level closes it; headings of a deeper level are parsed as nested blocks.
+// return values: false+nil=EOF, false+!nil=error,true+ nil continue, ie a
+// closing heading was put back for the caller to process
 func (p *Parser) parseBlock(parent BlockNode) bool {
 	for p.advance() {
-		if ln == p.lineNum {
-			break
-		}
-		ln = p.lineNum
 		p.ctx.Log("after parseblock.advance()=>", p.lineNum, p.node)
 		//we must have a valid non-comment node
 		switch n := p.node.(type) {
 		case *ttComment:
 		// continue
 		case *ttListItem:
-			parent.AddChild(newTextLine(n.Key()))
+			if p.markdown {
+				parent.AddChild(newTextLine("-" + n.Key()))
+				break
+			}
+			parent.AddChild(p.syntaxError(n.LineNum(), "list item outside a list"))
+		case *ttSyntaxError:
+			parent.AddChild(n)
 		case *ttTextLine, *ttEmpty:
 			p.ctx.Log("inside *ttTextLinei", n.Value())
 			parent.AddChild(n)
+		case Custom:
+			parent.AddChild(n)
 		case *ttBlock:
-			// p.retreat()
-			return true
-		// if p.Debug >= DebugAll {
-		// 	fmt.Printf(`**parseblock: parent= "%s" [%d]; current %s="%s"[%d]
-		// `, parent.Key(), parent.level, "block", n.Key(), n.level)
-		// }
-		// ok := p.parseBlock(n) //parse it passing this token as a parent
-		// if !ok {
-		// 	break
-		// }
+			if n.Level() <= parent.Level() { // a sibling or an ancestor's sibling
+				p.retreat()
+				return true
+			}
+			if n.Level() > parent.Level()+1 {
+				p.doc.addDiagnostic(n.LineNum(), SevWarning, "heading level %d follows a level %d heading; level %d skipped",
+					n.Level(), parent.Level(), parent.Level()+1)
+			}
+			parent.AddChild(n)
+			if !p.parseBlock(n) {
+				return false
+			}
 		case *ttList:
 			ok := p.parseList(n)
 			p.ctx.Log("in *ttlist case after returning from parseList")
 			parent.AddChild(n)
 			if !ok {
-				break
+				return false
 			}
 			p.retreat()
 		case *ttAttrib:
 			// p.Log("inside parseBlock.ttkvpair:", n.key, n.value)
-			p.doc.Attribs()[n.key] = n.value
+			// made available to the document once its block is known to be
+			// included (see Document.evalAllAttribs)
+			parent.addAttrib(n)
 		default:
-			panic(fmt.Sprintf("unhandled token type in parseBlock():line %d: %v reflect.type=%s", p.lineNum, n, reflect.TypeOf(n).String()))
+			parent.AddChild(p.syntaxError(p.lineNum, "unexpected %s", reflect.TypeOf(n).String()))
 		} //switch
 	} //for
 	return false // advanced returned false
@@ -222,7 +344,20 @@ func (p *Parser) parseList(list *ttList) bool {
 	return false //advance() failed
 }
 
-// }
+// escapableMarkers lists the characters that are not treated as markers at
+// the start of a line if preceded by a backslash
+const escapableMarkers = "#-~./"
+
+// isEscaped reports whether line starts with a backslash escaping a marker, a
+// plugin prefix or another such escape, eg \\# for a text line starting with \#
+func isEscaped(line string, plugins []*Plugin) bool {
+	if len(line) < 2 || line[0] != '\\' {
+		return false
+	}
+	rest := line[1:]
+	return strings.IndexByte(escapableMarkers, rest[0]) != -1 || pluginFor(plugins, rest) != nil || isEscaped(rest, plugins)
+}
+
 func (p *Parser) parseLine(line string) Node {
 	//scenario 1 : empty line
 	if line == "" {
@@ -234,6 +369,12 @@ func (p *Parser) parseLine(line string) Node {
 	}
 	//get the first unicode coding point guaranteed to have >=1 char b/c of the empty check above
 	switch ch := []rune(line)[0]; ch {
+	//scenario 9: a text line starting with an escaped marker eg \# not a heading
+	case '\\':
+		if isEscaped(line, p.ctx.Plugins) {
+			return newTextLine(line[1:])
+		}
+		return newTextLine(line)
 	//scenario 3: list item
 	case '-':
 		item := line[1:] //skip the minus
@@ -245,7 +386,7 @@ func (p *Parser) parseLine(line string) Node {
 		if level> -1 {
 			return newBlock(name, level)
 		}	
-		return newTextLine(line)
+		return newSyntaxError("invalid heading: expected one or more '#' followed by a space and a name")
 	case '~':
 		//scenario 7: a list
 		return newList(line[1:], 0)
@@ -257,9 +398,9 @@ func (p *Parser) parseLine(line string) Node {
 		}
 		// treat as attribute
 		parts := strings.SplitN(line[1:], ":", 2) //split on the first colon skipping the first char
-		// scenario 6, regular text starting with a dot, 1+ spaces and :
+		// scenario 6, attribute with an empty key
 		if strings.TrimSpace(parts[0]) == "" {
-			return newTextLine(line)
+			return newSyntaxError("attribute with an empty key")
 		}
 		//scenario 8: attribute; key:value
 		return newAttrib(parts[0], parts[1])
@@ -268,25 +409,61 @@ func (p *Parser) parseLine(line string) Node {
 	}
 }
 
-// readNextLine advances the scanner to the next line and return false
+// readNextLine advances the reader to the next line and return false
 // if EOF encountered or error occurred. Parser.Err() reports the specific error
 // otherwise it return true
 // first time called there is always something to read
 func (p *Parser) readLine() bool {
+	if p.unread {
+		p.unread = false
+		p.lineNum++
+		return true
+	}
 	if p.err != nil { //we have reached eof or encountered an error in previous call
 		return false
 	}
-	if p.scanner.Scan() {
-		p.line = p.scanner.Text()
-		p.lineNum++
-		p.ctx.Log("readLine()", p.lineNum, ":", p.line)
-		return true
+	line, err := p.readRawLine()
+	if err != nil {
+		//there was an error, set parser.errorState
+		p.err = err
+		return false
 	}
-	//there was an error, set parser.errorState
-	if p.scanner.Err() == nil { //eof reached
-		p.err = errEOF
-	} else { //read error
-		p.err = p.scanner.Err()
+	p.line = line
+	p.lineNum++
+	p.ctx.Log("readLine()", p.lineNum, ":", p.line)
+	return true
+}
+
+// unreadLine makes the next call to readLine return the current line again
+func (p *Parser) unreadLine() {
+	p.unread = true
+	p.lineNum--
+}
+
+// readRawLine reads one line of any length, stripping the line terminator.
+// The internal buffer only holds BufferCap bytes so longer lines are
+// assembled from fragments; if Options.MaxLineLength is > 0, lines longer
+// than that return an error; parse() reports it at the offending line.
+func (p *Parser) readRawLine() (string, error) {
+	var buf []byte
+	for {
+		frag, isPrefix, err := p.reader.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return "", errEOF
+			}
+			return "", err
+		}
+		if max := p.ctx.MaxLineLength; max > 0 && len(buf)+len(frag) > max {
+			return "", fmt.Errorf("line exceeds the maximum length of %d bytes", max)
+		}
+		if !isPrefix {
+			if buf == nil { // hot path: line fitted in the buffer
+				return string(frag), nil
+			}
+			return string(append(buf, frag...)), nil
+		}
+		// frag is only valid until the next read
+		buf = append(buf, frag...)
 	}
-	return false
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/drgo/booker/tu"
@@ -46,7 +48,51 @@ func TestParseFileBlock(t *testing.T) {
 	}
 	// t.Logf("%+v", doc)
 	tu.Equal(t, doc.root.Kind(), LtBlock)
-	tu.Equal(t, len(doc.root.Children()), 11)
+	// two level-1 sections and an empty line; everything else is nested
+	tu.Equal(t, len(doc.root.Children()), 3)
+}
+
+// TestParseNesting checks that every block is a child of the block named by
+// its .parent attribute in test/blocks.md
+func TestParseNesting(t *testing.T) {
+	doc, err := ctx.ParseFile("test/blocks.md", nil)
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	count := 0
+	var check func(parent BlockNode)
+	check = func(parent BlockNode) {
+		for _, c := range parent.Children() {
+			blk, ok := c.(BlockNode)
+			if !ok {
+				continue
+			}
+			count++
+			want, _ := blk.Attrib("parent")
+			tu.Equal(t, trimLower(parent.Key()), trimLower(want))
+			tu.Equal(t, blk.Level(), parent.Level()+1)
+			check(blk)
+		}
+	}
+	check(doc.root)
+	numBlocks, _ := doc.root.Attrib("NumBlocks")
+	tu.Equal(t, strconv.Itoa(count), numBlocks)
+}
+
+func TestParseSkippedLevel(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader("# Top\n### Deep\ntext\n## Middle\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	top := doc.root.NthChild(0).(BlockNode)
+	tu.Equal(t, len(top.Children()), 2)
+	tu.Equal(t, top.NthChild(0).Key(), "deep")
+	tu.Equal(t, top.NthChild(1).Key(), "middle")
+	tu.Equal(t, len(doc.Diagnostics()), 1)
+	tu.Equal(t, doc.Diagnostics()[0].Severity, SevWarning)
+	tu.Equal(t, doc.Diagnostics()[0].Line, 2)
 }
 
 
@@ -60,22 +106,134 @@ func TestParseFileLists(t *testing.T) {
 	tu.Equal(t, doc.root.Kind(), LtBlock)
 }
 
+// parseSpecs parses and evaluates test/specs.md. Its -- lines are list items
+// outside a list, so the errors for them are checked and the document built
+// by the recovering parser is returned.
+func parseSpecs(t *testing.T) *Document {
+	t.Helper()
+	p := NewParser(ctx, tu.File.MustRead("test/specs.md"))
+	p.doc.path = "test/specs.md"
+	tu.Equal(t, p.doc.loadDefaults(), nil)
+	diags, ok := p.parse().(Diagnostics)
+	tu.Equal(t, ok, true)
+	lines := []int{}
+	for _, d := range diags {
+		tu.Equal(t, d.Msg, "list item outside a list")
+		lines = append(lines, d.Line)
+	}
+	tu.Equal(t, lines, []int{25, 26, 28, 30})
+	tu.Equal(t, p.doc.eval(), nil)
+	return p.doc
+}
+
 func TestParse(t *testing.T) {
-	doc, err := ctx.ParseFile("", specs)
+	doc := parseSpecs(t)
+	t.Logf("printout of doc: \n %+v", doc)
+	tu.Equal(t, doc.root.Kind(), LtBlock)
+}
+
+func TestPrinter(t *testing.T) {
+	doc := parseSpecs(t)
+	t.Logf("\n\n%s\n", newPrinter().print(doc.root)) 
+}
+
+func TestParseLongLine(t *testing.T) {
+	long := strings.Repeat("x", 100*1024)
+	src := ".name: long\n" + long + "\nlast line\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
 	tu.Equal(t, err, nil)
 	if err != nil {
 		return
 	}
-	t.Logf("printout of doc: \n %+v", doc)
-	tu.Equal(t, doc.root.Kind(), LtBlock)
+	children := doc.root.Children()
+	tu.Equal(t, len(children), 2)
+	tu.Equal(t, children[0].Value(), long)
+
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.MaxLineLength = 1024
+	_, err = NewContext(opts).ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err != nil, true)
+	if err != nil {
+		tu.Equal(t, strings.Contains(err.Error(), "line 2:"), true)
+	}
 }
 
-func TestPrinter(t *testing.T) {
-	doc, err := ctx.ParseFile("", specs)
+func TestParseErrorRecovery(t *testing.T) {
+	src := `# Section 1
+##x
+. : no key
+- stray item
+## Sub
+## sub
+# Section 2
+## Sub
+`
+	doc, err := ctx.ParseFile("bad.md", strings.NewReader(src))
+	tu.Equal(t, doc == nil, true)
+	diags, ok := err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if !ok {
+		return
+	}
+	lines := []int{}
+	for _, d := range diags {
+		lines = append(lines, d.Line)
+	}
+	tu.Equal(t, lines, []int{2, 3, 4, 6})
+	tu.Equal(t, diags[0].Error(), "bad.md:2: invalid heading: expected one or more '#' followed by a space and a name")
+}
+
+func TestParseMultilineAttribs(t *testing.T) {
+	src := `.name: Lisa
+.abstract:
+<<< {name} plays
+    the saxophone
+>>>
+.summary: << a summary
+   on two lines >>
+.one: << all on one line >>
+text
+.unclosed: <<< never closed
+`
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	diags, ok := err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if ok {
+		tu.Equal(t, len(diags), 1)
+		tu.Equal(t, diags[0].Msg, "multi-line value of 'unclosed' not closed with '>>>'")
+	}
+	src = src[:strings.Index(src, ".unclosed")]
+	doc, err = ctx.ParseFile("", strings.NewReader(src))
 	tu.Equal(t, err, nil)
 	if err != nil {
 		return
 	}
-	t.Logf("\n\n%s\n", newPrinter().print(doc.root)) 
+	tu.Equal(t, doc.attribs["abstract"], "Lisa plays\n    the saxophone")
+	tu.Equal(t, doc.attribs["summary"], "a summary on two lines")
+
+	// a line too long to read is reported once, not as an unclosed value
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.MaxLineLength = 64
+	_, err = NewContext(opts).ParseFile("", strings.NewReader(".long: <<<\n"+strings.Repeat("x", 100)+"\n>>>\n"))
+	diags, ok = err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if ok {
+		tu.Equal(t, len(diags), 1)
+		tu.Equal(t, strings.Contains(diags[0].Msg, "not closed"), false)
+	}
+	tu.Equal(t, doc.attribs["one"], "all on one line")
+	tu.Equal(t, doc.attribLine("summary"), 6)
+	tu.Equal(t, len(doc.root.Children()), 1)
+	tu.Equal(t, doc.root.NthChild(0).LineNum(), 9)
 }
 
+func TestParseStrayListItem(t *testing.T) {
+	_, err := ctx.ParseFile("test/stray.md", nil)
+	diags, ok := err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if !ok {
+		return
+	}
+	tu.Equal(t, len(diags), 1)
+	tu.Equal(t, diags[0].Error(), "test/stray.md:7: list item outside a list")
+}
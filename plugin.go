@@ -0,0 +1,212 @@
+package mdson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Plugin claims source lines starting with its Prefix so that applications
+// can add domain-specific constructs without changing the parser, eg
+//
+//	!chart bar sales.csv
+//	@cite knuth84
+//
+// A line is claimed if it starts with Prefix followed by a space or the end of
+// the line; the rest of the line is the directive's arguments. If End is set,
+// the lines following the directive up to a line consisting of End make up
+// its body (a block directive), eg
+//
+//	!chart bar
+//	jan 10
+//	feb 12
+//	!end
+//
+// Lines claimed by plugins are neither evaluated nor otherwise interpreted;
+// a line starting with a prefix can be escaped with a backslash like other
+// markers. Plugins are registered with Options.AddPlugin.
+type Plugin struct {
+	// the text claimed lines start with, eg !chart
+	Prefix string
+	// if set, the directive extends to a line consisting of End
+	End string
+	// returns the node for a directive; if nil, a *CustomNode is used. Nodes
+	// of other types must embed the *CustomNode returned by NewCustomNode
+	Parse func(d *Directive) (Custom, error)
+	// functions rendering the plugin's nodes keyed by the name of the output
+	// format: md, html, mom, latex, json or mdson. Nodes are omitted from
+	// formats without one except for mdson, where the directive's source is
+	// written, and json, where it is written as a "custom" node. The output
+	// of a json hook must be a JSON value. The first error returned by a hook
+	// is returned by the Transformer.
+	Render map[string]RenderFunc
+}
+
+// RenderFunc writes a node produced by a plugin to w
+type RenderFunc func(w io.Writer, n Custom) error
+
+// AddPlugin registers p; it returns an error if p's prefix is empty, could be
+// taken for an MDSon marker or is already claimed by another plugin
+func (po *Options) AddPlugin(p *Plugin) error {
+	if p.Prefix == "" || strings.ContainsAny(p.Prefix, " \t") {
+		return fmt.Errorf("invalid plugin prefix '%s'", p.Prefix)
+	}
+	if strings.IndexByte(escapableMarkers+`\`, p.Prefix[0]) != -1 || strings.HasPrefix(p.Prefix, "//") {
+		return fmt.Errorf("plugin prefix '%s' starts with an MDSon marker", p.Prefix)
+	}
+	for _, q := range po.Plugins {
+		if q.Prefix == p.Prefix {
+			return fmt.Errorf("plugin prefix '%s' already registered", p.Prefix)
+		}
+	}
+	po.Plugins = append(po.Plugins, p)
+	return nil
+}
+
+// pluginFor returns the plugin claiming line if any
+func pluginFor(plugins []*Plugin, line string) *Plugin {
+	for _, p := range plugins {
+		if rest := strings.TrimPrefix(line, p.Prefix); len(rest) < len(line) &&
+			(rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return p
+		}
+	}
+	return nil
+}
+
+// A Directive holds the lines claimed by a plugin
+type Directive struct {
+	// the plugin's prefix, eg !chart
+	Name string
+	// the rest of the first line with surrounding spaces removed
+	Args string
+	// the lines of a block directive excluding the first and the end line
+	Body []string
+	// the line number of the first line
+	Line   int
+	line   string
+	plugin *Plugin
+}
+
+// Source returns the lines of d as written
+func (d *Directive) Source() []string {
+	src := append([]string{d.line}, d.Body...)
+	if d.plugin != nil && d.plugin.End != "" {
+		src = append(src, d.plugin.End)
+	}
+	return src
+}
+
+// Custom is the interface implemented by nodes produced by plugins
+type Custom interface {
+	Node
+	Directive() *Directive
+}
+
+var _ Custom = &CustomNode{}
+
+// CustomNode is the node produced for a directive by plugins without a Parse
+// function; custom node types embed it. Its Key is the directive's name and
+// its Value the first line.
+type CustomNode struct {
+	*ttBase
+	d *Directive
+}
+
+// NewCustomNode returns a node for d
+func NewCustomNode(d *Directive) *CustomNode {
+	n := &CustomNode{ttBase: newBase(LtCustom, d.Name), d: d}
+	n.SetLineNum(d.Line)
+	return n
+}
+
+// Directive returns the lines claimed for n
+func (n *CustomNode) Directive() *Directive {
+	return n.d
+}
+
+func (n CustomNode) Value() string {
+	return n.d.line
+}
+
+// SetValue is a no-op; a directive's lines are only changed through Directive()
+func (n *CustomNode) SetValue(s string) Node {
+	return n
+}
+
+func (n CustomNode) String() string {
+	return fmt.Sprintf(nodeDescLine, n.Kind(), n.LineNum(), n.Level(), n.Key(), n.Value())
+}
+
+// parseDirective reads the body of a block directive starting with the
+// current line and returns the plugin's node for it
+func (p *Parser) parseDirective(pl *Plugin) Node {
+	lnum := p.lineNum
+	d := &Directive{
+		Name:   pl.Prefix,
+		Args:   strings.TrimSpace(p.line[len(pl.Prefix):]),
+		Line:   lnum,
+		line:   p.line,
+		plugin: pl,
+	}
+	if pl.End != "" {
+		for {
+			if !p.readLine() {
+				return p.syntaxError(lnum, "directive '%s' not closed with '%s'", pl.Prefix, pl.End)
+			}
+			if strings.TrimSpace(p.line) == pl.End {
+				break
+			}
+			d.Body = append(d.Body, p.line)
+		}
+	}
+	if pl.Parse == nil {
+		return NewCustomNode(d)
+	}
+	n, err := pl.Parse(d)
+	if err != nil {
+		return p.syntaxError(lnum, "%s: %s", pl.Prefix, err)
+	}
+	if n == nil || n.Directive() == nil || n.Kind() != LtCustom {
+		return p.syntaxError(lnum, "%s: plugin returned an invalid node", pl.Prefix)
+	}
+	n.SetLineNum(lnum)
+	return n
+}
+
+// plugins returns the plugins registered for doc's context
+func (doc *Document) plugins() []*Plugin {
+	if doc.ctx == nil {
+		return nil
+	}
+	return doc.ctx.Plugins
+}
+
+// custom writes n using the hook registered by its plugin for p.format and
+// reports whether there was one. The first error returned by a hook is kept
+// for renderErr.
+func (p printer) custom(n Custom) bool {
+	d := n.Directive()
+	for _, pl := range p.plugins {
+		if pl.Prefix != d.Name {
+			continue
+		}
+		render := pl.Render[p.format]
+		if render == nil {
+			return false
+		}
+		if err := render(p.w, n); err != nil && p.hookErr != nil && *p.hookErr == nil {
+			*p.hookErr = fmt.Errorf("line %d: %s: %s", d.Line, d.Name, err)
+		}
+		return true
+	}
+	return false
+}
+
+// renderErr returns the first error returned by a render hook
+func (p printer) renderErr() error {
+	if p.hookErr == nil {
+		return nil
+	}
+	return *p.hookErr
+}
@@ -0,0 +1,158 @@
+package mdson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+// chart is a custom node holding the data of a !chart directive
+type chart struct {
+	*CustomNode
+	kind   string
+	values []string
+}
+
+func chartPlugin() *Plugin {
+	return &Plugin{
+		Prefix: "!chart",
+		End:    "!end",
+		Parse: func(d *Directive) (Custom, error) {
+			if d.Args == "" {
+				return nil, fmt.Errorf("missing chart type")
+			}
+			c := &chart{CustomNode: NewCustomNode(d), kind: d.Args}
+			for _, line := range d.Body {
+				if f := strings.Fields(line); len(f) == 2 {
+					c.values = append(c.values, f[1])
+				}
+			}
+			return c, nil
+		},
+		Render: map[string]RenderFunc{
+			"html": func(w io.Writer, n Custom) error {
+				c := n.(*chart)
+				_, err := fmt.Fprintf(w, "<chart type=%q data=%q></chart>"+EOL, c.kind, strings.Join(c.values, ","))
+				return err
+			},
+			"json": func(w io.Writer, n Custom) error {
+				_, err := fmt.Fprintf(w, "[%s]", strings.Join(n.(*chart).values, ","))
+				return err
+			},
+		},
+	}
+}
+
+const pluginSrc = `# Sales
+@cite knuth84
+!chart bar
+jan 10
+feb 12
+!end
+\@cite not a citation
+`
+
+func pluginContext(t *testing.T) *Context {
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	tu.Equal(t, opts.AddPlugin(chartPlugin()), nil)
+	tu.Equal(t, opts.AddPlugin(&Plugin{
+		Prefix: "@cite",
+		Render: map[string]RenderFunc{
+			"md": func(w io.Writer, n Custom) error {
+				_, err := fmt.Fprint(w, "[^"+n.Directive().Args+"]"+EOL)
+				return err
+			},
+		},
+	}), nil)
+	return NewContext(opts)
+}
+
+func TestPluginParse(t *testing.T) {
+	doc, err := pluginContext(t).ParseFile("", strings.NewReader(pluginSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	sales := doc.root.NthChild(0).(BlockNode)
+	tu.Equal(t, len(sales.Children()), 3)
+	cite := sales.NthChild(0).(Custom)
+	tu.Equal(t, cite.Kind(), LtCustom)
+	tu.Equal(t, cite.Directive().Name, "@cite")
+	tu.Equal(t, cite.Directive().Args, "knuth84")
+	c, ok := sales.NthChild(1).(*chart)
+	tu.Equal(t, ok, true)
+	if ok {
+		tu.Equal(t, c.kind, "bar")
+		tu.Equal(t, c.values, []string{"10", "12"})
+		tu.Equal(t, c.LineNum(), 3)
+		tu.Equal(t, c.Directive().Source(), []string{"!chart bar", "jan 10", "feb 12", "!end"})
+	}
+	tu.Equal(t, sales.NthChild(2).Kind(), LtTextLine)
+	tu.Equal(t, sales.NthChild(2).Value(), "@cite not a citation")
+}
+
+func TestPluginErrors(t *testing.T) {
+	opts := DefaultOptions()
+	tu.Equal(t, opts.AddPlugin(&Plugin{Prefix: "#x"}) != nil, true)
+	tu.Equal(t, opts.AddPlugin(&Plugin{Prefix: ""}) != nil, true)
+	tu.Equal(t, opts.AddPlugin(&Plugin{Prefix: "!x"}), nil)
+	tu.Equal(t, opts.AddPlugin(&Plugin{Prefix: "!x"}) != nil, true)
+
+	ctx := pluginContext(t)
+	_, err := ctx.ParseFile("", strings.NewReader("!chart\n!end\n"))
+	tu.Equal(t, err != nil && strings.Contains(err.Error(), "missing chart type"), true)
+	_, err = ctx.ParseFile("", strings.NewReader("!chart bar\njan 10\n"))
+	tu.Equal(t, err != nil && strings.Contains(err.Error(), "not closed with '!end'"), true)
+}
+
+func TestPluginRender(t *testing.T) {
+	doc, err := pluginContext(t).ParseFile("", strings.NewReader(pluginSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	render := func(tr Transformer) string {
+		var buf bytes.Buffer
+		tu.Equal(t, tr.Transform(&buf, doc), nil)
+		return strings.ReplaceAll(buf.String(), EOL, "\n")
+	}
+	cfg := DefaultTransformerConfig()
+	tu.Equal(t, render(NewMDTransformer(cfg)), "# Sales\n[^knuth84]\n@cite not a citation\n")
+	html := NewHTMLTransformer(cfg)
+	html.Fragment = true
+	tu.Equal(t, render(html), `<section id="sales">`+"\n<h1>Sales</h1>\n"+
+		`<chart type="bar" data="10,12"></chart>`+"\n<p>@cite not a citation</p>\n</section>\n")
+	tu.Equal(t, render(NewJSONTransformer(TransformerConfig{})), `{"children":[{"type":"block","name":"Sales","level":1,"children":[`+
+		`{"type":"custom","name":"@cite","value":"knuth84"},`+
+		`{"type":"custom","name":"!chart","value":"bar","items":["jan 10","feb 12"],"data":[10,12]},`+
+		`{"type":"text","value":"@cite not a citation"}]}]}`+"\n")
+	// the formatter writes the source of directives and escapes claimed text
+	var buf bytes.Buffer
+	tu.Equal(t, Format(&buf, doc), nil)
+	tu.Equal(t, strings.ReplaceAll(buf.String(), EOL, "\n"), pluginSrc)
+}
+
+func TestPluginRenderError(t *testing.T) {
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	fail := func(w io.Writer, n Custom) error { return fmt.Errorf("no data") }
+	tu.Equal(t, opts.AddPlugin(&Plugin{
+		Prefix: "!chart",
+		Render: map[string]RenderFunc{"md": fail, "html": fail, "latex": fail, "mom": fail, "json": fail, "mdson": fail},
+	}), nil)
+	doc, err := NewContext(opts).ParseFile("", strings.NewReader("# Sales\n!chart bar\n!chart pie\n"))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	for _, name := range DefaultFormats().Names() {
+		tr, err := DefaultFormats().New(name)
+		tu.Equal(t, err, nil)
+		err = tr.Transform(io.Discard, doc)
+		tu.Equal(t, err != nil && err.Error() == "line 2: !chart: no data", true)
+	}
+}
@@ -0,0 +1,261 @@
+// Package preview serves a directory of .mdson files rendered as HTML for
+// previewing documents while editing them. Pages open in a browser reload
+// when one of their sources changes. The server only answers requests made
+// to a loopback address.
+package preview
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drgo/mdson"
+	"github.com/drgo/mdson/watch"
+)
+
+// EventsPath is the URL path of the server-sent events stream that notifies
+// pages of changes to their sources
+const EventsPath = "/_mdson/events"
+
+// reloadScript is appended to every page; it reloads the page when the
+// server sends a reload event for it
+const reloadScript = `<script>
+new EventSource("` + EventsPath + `?page=" + encodeURIComponent(location.pathname))
+	.addEventListener("reload", function() { location.reload(); });
+</script>`
+
+// Config controls a preview server
+type Config struct {
+	// directory served
+	Root string
+	// parsing options; mdson.DefaultOptions() if nil
+	Options *mdson.Options
+	// output formats; pages are rendered by the html one with its options,
+	// eg those loaded from a site's configuration. mdson.DefaultFormats() if nil
+	Formats mdson.FormatMap
+	// how often sources are checked for changes; watch.DefaultInterval if 0
+	Interval time.Duration
+}
+
+// Server is an http.Handler that renders a.mdson when a.html is requested,
+// dir/index.mdson or a listing of the .mdson files in dir when dir/ is
+// requested, and serves other files as they are.
+type Server struct {
+	cfg     Config
+	ctx     *mdson.Context
+	watcher *watch.Watcher
+	files   http.Handler
+	mu      sync.Mutex
+	// pages (URL paths) rendered from each watched file
+	deps map[string]map[string]bool
+	// reload channels of the browsers showing each page
+	clients map[string]map[chan struct{}]bool
+}
+
+// New returns a Server; call Run to start watching sources
+func New(cfg Config) *Server {
+	opts := cfg.Options
+	if opts == nil {
+		opts = mdson.DefaultOptions()
+	}
+	if cfg.Formats == nil {
+		cfg.Formats = mdson.DefaultFormats()
+	}
+	return &Server{
+		cfg:     cfg,
+		ctx:     mdson.NewContext(opts),
+		watcher: watch.New(cfg.Interval),
+		files:   http.FileServer(http.Dir(cfg.Root)),
+		deps:    make(map[string]map[string]bool),
+		clients: make(map[string]map[chan struct{}]bool),
+	}
+}
+
+// Run watches the sources of the pages served so far until ctx is done
+func (s *Server) Run(ctx context.Context) {
+	s.watcher.Run(ctx, s.reload)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isLoopback(r.Host) || !isLoopback(r.RemoteAddr) {
+		http.Error(w, "preview is only available from this computer", http.StatusForbidden)
+		return
+	}
+	upath := path.Clean("/" + r.URL.Path)
+	if upath == EventsPath {
+		s.serveEvents(w, r)
+		return
+	}
+	name := filepath.Join(s.cfg.Root, filepath.FromSlash(upath))
+	switch fi, err := os.Stat(name); {
+	case err == nil && fi.IsDir():
+		if index := filepath.Join(name, "index"+mdson.SourceExt); exists(index) {
+			s.servePage(w, upath, index)
+		} else {
+			s.serveListing(w, upath, name)
+		}
+	case path.Ext(upath) == ".html" && exists(strings.TrimSuffix(name, ".html")+mdson.SourceExt):
+		s.servePage(w, upath, strings.TrimSuffix(name, ".html")+mdson.SourceExt)
+	default:
+		s.files.ServeHTTP(w, r)
+	}
+}
+
+// servePage renders the source src requested as upath
+func (s *Server) servePage(w http.ResponseWriter, upath, src string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	doc, err := s.ctx.ParseFile(src, nil)
+	if err != nil {
+		// keep watching the files so that the page reloads once fixed
+		deps := []string{src}
+		if s.ctx.DefaultsFile != "" {
+			deps = append(deps, s.ctx.DefaultsFile)
+		}
+		s.track(upath, deps)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Error</title>\n</head>\n<body>\n<pre>%s</pre>\n%s\n</body>\n</html>\n",
+			html.EscapeString(err.Error()), reloadScript)
+		return
+	}
+	s.track(upath, doc.Sources())
+	f, err := s.cfg.Formats.Get("html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t := f.Transformer()
+	if h, ok := t.(*mdson.HTMLTransformer); ok {
+		h.Footer = reloadScript
+	} else {
+		defer fmt.Fprintln(w, reloadScript)
+	}
+	if err := t.Transform(w, doc); err != nil {
+		s.ctx.Log("preview: error rendering", src, err)
+	}
+}
+
+// serveListing writes links to the pages and subdirectories of dir
+func (s *Server) serveListing(w http.ResponseWriter, upath, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// reload when a source is added or removed
+	s.track(upath, []string{dir})
+	var links []string
+	for _, e := range entries {
+		switch name := e.Name(); {
+		case strings.HasPrefix(name, "."):
+		case e.IsDir():
+			links = append(links, name+"/")
+		case filepath.Ext(name) == mdson.SourceExt:
+			links = append(links, strings.TrimSuffix(name, mdson.SourceExt)+".html")
+		}
+	}
+	sort.Strings(links)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	base := strings.TrimSuffix(upath, "/") + "/"
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n<ul>\n",
+		html.EscapeString(upath), html.EscapeString(upath))
+	for _, l := range links {
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(base+l), html.EscapeString(l))
+	}
+	fmt.Fprintf(w, "</ul>\n%s\n</body>\n</html>\n", reloadScript)
+}
+
+// track records that page upath was rendered from files
+func (s *Server) track(upath string, files []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range files {
+		if s.deps[f] == nil {
+			s.deps[f] = make(map[string]bool)
+		}
+		s.deps[f][upath] = true
+	}
+	s.watcher.Add(files...)
+}
+
+// serveEvents streams a reload event whenever a source of the page given
+// by the page query parameter changes
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	page := path.Clean("/" + r.URL.Query().Get("page"))
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.clients[page] == nil {
+		s.clients[page] = make(map[chan struct{}]bool)
+	}
+	s.clients[page][ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients[page], ch)
+		s.mu.Unlock()
+	}()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, ": watching "+page+"\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: "+page+"\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// reload notifies the browsers showing pages rendered from the changed files
+func (s *Server) reload(changed []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range changed {
+		for page := range s.deps[f] {
+			s.ctx.Log("preview: reloading", page, "after a change to", f)
+			for ch := range s.clients[page] {
+				select {
+				case ch <- struct{}{}:
+				default: // a reload is already pending
+				}
+			}
+		}
+	}
+}
+
+// isLoopback reports whether the host part of addr, a host or host:port,
+// is localhost or a loopback IP address
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+func exists(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && !fi.IsDir()
+}
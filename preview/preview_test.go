@@ -0,0 +1,112 @@
+package preview
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+	"github.com/drgo/mdson"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "intro.mdson"), []byte(".title: Intro\n# Introduction\nsome <text>\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "bad.mdson"), []byte("##bad\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "logo.txt"), []byte("logo"), 0o644)
+	s := New(Config{Root: dir, Options: mdson.DefaultOptions().SetDebug(ui.DebugSilent)})
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts, dir
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestServe(t *testing.T) {
+	_, ts, _ := newTestServer(t)
+	code, body := get(t, ts.URL+"/intro.html")
+	tu.Equal(t, code, http.StatusOK)
+	tu.Equal(t, strings.Contains(body, "<title>Intro</title>"), true)
+	tu.Equal(t, strings.Contains(body, "<h1>Introduction</h1>"), true)
+	tu.Equal(t, strings.Contains(body, "some &lt;text&gt;"), true)
+	tu.Equal(t, strings.Contains(body, EventsPath), true)
+
+	code, body = get(t, ts.URL+"/bad.html")
+	tu.Equal(t, code, http.StatusInternalServerError)
+	tu.Equal(t, strings.Contains(body, "invalid heading"), true)
+
+	code, body = get(t, ts.URL+"/")
+	tu.Equal(t, code, http.StatusOK)
+	tu.Equal(t, strings.Contains(body, `<a href="/intro.html">`), true)
+
+	code, body = get(t, ts.URL+"/logo.txt")
+	tu.Equal(t, code, http.StatusOK)
+	tu.Equal(t, body, "logo")
+}
+
+func TestServeFormatOptions(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "intro.mdson"), []byte("# Introduction\n"), 0o644)
+	fm := mdson.DefaultFormats()
+	fm["html"].Config.Font = "Georgia"
+	fm["html"].Config.NumberHeadings = true
+	ts := httptest.NewServer(New(Config{Root: dir, Options: mdson.DefaultOptions().SetDebug(ui.DebugSilent), Formats: fm}))
+	defer ts.Close()
+	code, body := get(t, ts.URL+"/intro.html")
+	tu.Equal(t, code, http.StatusOK)
+	tu.Equal(t, strings.Contains(body, `font-family: "Georgia";`), true)
+	tu.Equal(t, strings.Contains(body, "<h1>1 Introduction</h1>"), true)
+	tu.Equal(t, strings.Contains(body, EventsPath), true)
+}
+
+func TestServeLoopbackOnly(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	r := httptest.NewRequest("GET", "http://example.com/intro.html", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	tu.Equal(t, w.Code, http.StatusForbidden)
+	r = httptest.NewRequest("GET", "http://localhost:5500/intro.html", nil)
+	r.RemoteAddr = "[::1]:5000"
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	tu.Equal(t, w.Code, http.StatusOK)
+}
+
+func TestReload(t *testing.T) {
+	s, ts, dir := newTestServer(t)
+	get(t, ts.URL+"/intro.html")
+	resp, err := http.Get(ts.URL + EventsPath + "?page=/intro.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	tu.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	lines := bufio.NewReader(resp.Body)
+	line, _ := lines.ReadString('\n')
+	tu.Equal(t, line, ": watching /intro.html\n")
+
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "intro.mdson"), later, later)
+	s.reload(s.watcher.Poll())
+	lines.ReadString('\n') // blank line ending the comment
+	line, _ = lines.ReadString('\n')
+	tu.Equal(t, line, "event: reload\n")
+}
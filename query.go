@@ -0,0 +1,235 @@
+package mdson
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Paths select nodes of a document tree. A path is a list of segments
+// separated by slashes, each naming a child block or ~list of the nodes
+// selected by the previous segments, starting from the root, eg
+//
+//	job/document/sections list/section1
+//
+// Names are matched ignoring case and may contain the wildcards * (any
+// sequence of characters) and ? (any character). A segment of ** selects the
+// current nodes and all their descendant blocks and lists. A segment ending
+// with [n] selects the n-th item of a list or the n-th child block of a
+// block; n counts from 0 and negative values count from the end. The last
+// segment may end with .attribute to select an attribute declared in the
+// block, eg
+//
+//	job/document/sections list/section1.InputDir
+//	job/*.Command
+//	.title
+//
+// A name containing a dot is matched as a whole before being split into a
+// block name and an attribute name.
+
+// ErrNoMatch is returned (wrapped) by Get when no node matches a path
+var ErrNoMatch = errors.New("no node matches the path")
+
+// segment is a parsed path segment
+type segment struct {
+	name  string
+	index *int
+}
+
+// parseSegment parses a name optionally followed by [n]
+func parseSegment(s string) (segment, error) {
+	seg := segment{name: s}
+	if !strings.HasSuffix(s, "]") {
+		return seg, nil
+	}
+	open := strings.LastIndex(s, "[")
+	if open == -1 {
+		return seg, fmt.Errorf("invalid path segment '%s': missing '['", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s[open+1 : len(s)-1]))
+	if err != nil {
+		return seg, fmt.Errorf("invalid index in path segment '%s'", s)
+	}
+	seg.name, seg.index = s[:open], &n
+	return seg, nil
+}
+
+// matchName reports whether the name of a block, list or attribute matches pattern
+func matchName(pattern, name string) bool {
+	name = trimLower(strings.TrimSuffix(strings.TrimSpace(name), ":"))
+	ok, err := path.Match(trimLower(pattern), name)
+	return ok && err == nil
+}
+
+// Select returns the nodes matching the path pattern in document order.
+// Attributes are returned as nodes whose Value is the attribute's value.
+// It returns an error only if pattern is malformed.
+func (doc *Document) Select(pattern string) ([]Node, error) {
+	segs := strings.Split(pattern, "/")
+	cur := []Node{doc.root}
+	for i, s := range segs {
+		next, err := step(cur, s)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(segs)-1 && len(next) == 0 {
+			if dot := strings.LastIndex(s, "."); dot != -1 {
+				blocks := cur
+				if s[:dot] != "" {
+					if blocks, err = step(cur, s[:dot]); err != nil {
+						return nil, err
+					}
+				}
+				next = attribs(blocks, s[dot+1:])
+			}
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// step returns the nodes selected by the segment s from the nodes cur
+func step(cur []Node, s string) ([]Node, error) {
+	if s == "**" {
+		return descendants(cur), nil
+	}
+	seg, err := parseSegment(s)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := path.Match(seg.name, ""); err != nil {
+		return nil, fmt.Errorf("invalid path segment '%s': %s", s, err)
+	}
+	return children(cur, seg), nil
+}
+
+// children returns the child blocks and lists of nodes matching seg
+func children(nodes []Node, seg segment) []Node {
+	var found []Node
+	for _, n := range nodes {
+		blk, ok := n.(BlockNode)
+		if !ok {
+			continue
+		}
+		for _, c := range blk.Children() {
+			if (c.Kind() == LtBlock || c.Kind() == LtList) && matchName(seg.name, c.Value()) {
+				if seg.index == nil {
+					found = append(found, c)
+				} else if item := nth(c.(BlockNode), *seg.index); item != nil {
+					found = append(found, item)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// nth returns the n-th item of a list or the n-th child block of a block
+func nth(blk BlockNode, n int) Node {
+	kind := LtBlock
+	if blk.Kind() == LtList {
+		kind = LtListItem
+	}
+	var items []Node
+	for _, c := range blk.Children() {
+		if c.Kind() == kind {
+			items = append(items, c)
+		}
+	}
+	if n < 0 {
+		n += len(items)
+	}
+	if n < 0 || n >= len(items) {
+		return nil
+	}
+	return items[n]
+}
+
+// attribs returns the attributes of blocks whose name matches pattern
+func attribs(blocks []Node, pattern string) []Node {
+	var found []Node
+	for _, n := range blocks {
+		if blk, ok := n.(BlockNode); ok {
+			for _, a := range blk.attribNodes() {
+				if matchName(pattern, a.key) {
+					found = append(found, a)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// descendants returns nodes and all the blocks and lists they contain
+func descendants(nodes []Node) []Node {
+	var found []Node
+	var walk func(n Node)
+	walk = func(n Node) {
+		found = append(found, n)
+		if blk, ok := n.(BlockNode); ok && n.Kind() == LtBlock {
+			for _, c := range blk.Children() {
+				if c.Kind() == LtBlock || c.Kind() == LtList {
+					walk(c)
+				}
+			}
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	return found
+}
+
+// Get returns the single node matching path
+func (doc *Document) Get(path string) (Node, error) {
+	nodes, err := doc.Select(path)
+	if err != nil {
+		return nil, err
+	}
+	switch len(nodes) {
+	case 0:
+		return nil, fmt.Errorf("'%s': %w", path, ErrNoMatch)
+	case 1:
+		return nodes[0], nil
+	}
+	return nil, fmt.Errorf("'%s' matches %d nodes", path, len(nodes))
+}
+
+// GetValue returns the value of the single node matching path converted to
+// typ. The value of an attribute, list item or text line is its text; the
+// value of a list is its items separated by commas. Conversion errors are of
+// type *AttribError.
+func (doc *Document) GetValue(path string, typ AttribType) (interface{}, error) {
+	n, err := doc.Get(path)
+	if err != nil {
+		return nil, err
+	}
+	var s string
+	switch n.Kind() {
+	case LtBlock:
+		return nil, fmt.Errorf("'%s' is a block, not a value", path)
+	case LtList:
+		items := []string{}
+		for _, c := range n.(BlockNode).Children() {
+			if c.Kind() == LtListItem {
+				items = append(items, strings.TrimSpace(c.Value()))
+			}
+		}
+		if typ == TypeList {
+			return items, nil
+		}
+		s = strings.Join(items, ", ")
+	default:
+		s = strings.TrimSpace(n.Value())
+	}
+	v, err := coerce(s, typ)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok { // drop the duplicate value from the message
+			err = ne.Err
+		}
+		return nil, &AttribError{Key: path, Line: n.LineNum(), Type: typ, Value: s, Err: err}
+	}
+	return v, nil
+}
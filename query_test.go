@@ -0,0 +1,101 @@
+package mdson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+const querySrc = `.title: conversion job
+# Job
+.Command: run
+.Debug: 2
+~InputFileNames
+- tab1old.html
+- test.html
+## Document
+### Sections List
+#### Section1
+.InputDir: cmd
+.Weight: 1.5
+#### Section2
+.InputDir: docs
+#### Section 2.1
+.InputDir: docs/v2
+`
+
+func values(nodes []Node) []string {
+	vals := []string{}
+	for _, n := range nodes {
+		vals = append(vals, strings.TrimSpace(n.Value()))
+	}
+	return vals
+}
+
+func TestSelect(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(querySrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"job/document/sections list/section1.InputDir", []string{"cmd"}},
+		{"JOB/Document/Sections List/Section 2.1", []string{"Section 2.1"}},
+		{"job/document/sections list/section 2.1.inputdir", []string{"docs/v2"}},
+		{"job/document/sections list/section*.inputdir", []string{"cmd", "docs", "docs/v2"}},
+		{"job/document/sections list/section?", []string{"Section1", "Section2"}},
+		{"job/document/sections list[1].inputdir", []string{"docs"}},
+		{"job/document/sections list[-1]", []string{"Section 2.1"}},
+		{"job/document/sections list[3]", []string{}},
+		{"job/inputfilenames[0]", []string{"tab1old.html"}},
+		{"job/inputfilenames", []string{"InputFileNames"}},
+		{"**.inputdir", []string{"cmd", "docs", "docs/v2"}},
+		{"**/section2", []string{"Section2"}},
+		{"job.*", []string{"run", "2"}},
+		{".title", []string{"conversion job"}},
+		{"job/nothing", []string{}},
+	}
+	for _, tt := range tests {
+		nodes, err := doc.Select(tt.path)
+		tu.Equal(t, err, nil)
+		tu.Equal(t, values(nodes), tt.want)
+	}
+	_, err = doc.Select("job/list[x]")
+	tu.Equal(t, err.Error(), "invalid index in path segment 'list[x]'")
+}
+
+func TestGet(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(querySrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	n, err := doc.Get("job/document")
+	tu.Equal(t, err, nil)
+	tu.Equal(t, n.Kind(), LtBlock)
+	_, err = doc.Get("job/document/missing")
+	tu.Equal(t, errors.Is(err, ErrNoMatch), true)
+	_, err = doc.Get("**.inputdir")
+	tu.Equal(t, err.Error(), "'**.inputdir' matches 3 nodes")
+
+	v, err := doc.GetValue("job.debug", TypeInt)
+	tu.Equal(t, err, nil)
+	tu.Equal(t, v, 2)
+	v, err = doc.GetValue("job/document/sections list/section1.weight", TypeFloat)
+	tu.Equal(t, err, nil)
+	tu.Equal(t, v, 1.5)
+	v, err = doc.GetValue("job/inputfilenames", TypeList)
+	tu.Equal(t, err, nil)
+	tu.Equal(t, v, []string{"tab1old.html", "test.html"})
+	_, err = doc.GetValue("job.command", TypeBool)
+	var ae *AttribError
+	tu.Equal(t, errors.As(err, &ae), true)
+	tu.Equal(t, ae.Line, 3)
+	_, err = doc.GetValue("job", TypeString)
+	tu.Equal(t, err.Error(), "'job' is a block, not a value")
+}
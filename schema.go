@@ -0,0 +1,451 @@
+package mdson
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema describes the blocks and attributes a document is expected to have.
+// A schema can be written in MDSon (see ParseSchema) or derived from a Go
+// struct (see SchemaFromStruct).
+type Schema struct {
+	Root *BlockSchema
+}
+
+// BlockSchema describes a block. If its name ends with " list", the block is a
+// list of blocks and every child block is checked against Blocks[0].
+type BlockSchema struct {
+	Name     string
+	Required bool
+	// bounds on the number of child blocks of a list of blocks; 0 means no bound
+	MinItems int
+	MaxItems int
+	// if true, undeclared attributes and blocks are reported as errors rather than warnings
+	Strict  bool
+	Attribs []*AttribSchema
+	Blocks  []*BlockSchema
+}
+
+// AttribSchema describes an attribute. Attributes of TypeList can also be
+// supplied as a ~list of the same name.
+type AttribSchema struct {
+	Name     string
+	Type     AttribType
+	Required bool
+	// permitted values (compared ignoring case); empty means any value
+	Allowed []string
+	// bounds on the number of items of a TypeList attribute; 0 means no bound
+	MinItems int
+	MaxItems int
+}
+
+func (bs *BlockSchema) isList() bool {
+	return isArray(trimLower(bs.Name))
+}
+
+func (bs *BlockSchema) block(name string) *BlockSchema {
+	for _, b := range bs.Blocks {
+		if trimLower(b.Name) == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func (bs *BlockSchema) attrib(name string) *AttribSchema {
+	for _, a := range bs.Attribs {
+		if trimLower(a.Name) == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// ParseAttribType returns the AttribType named s, eg "int" or "time"
+func ParseAttribType(s string) (AttribType, error) {
+	for t := TypeString; t <= TypeList; t++ {
+		if t.String() == trimLower(s) {
+			return t, nil
+		}
+	}
+	return TypeString, fmt.Errorf("unknown attribute type '%s'", s)
+}
+
+// ParseSchema parses a schema written in MDSon. The schema mirrors the
+// documents it describes: each heading declares a block and each attribute
+// declares an attribute, its value being the type optionally followed by
+// any of: required, oneof=a|b|c, min=n and max=n, eg
+//
+//	# Job
+//	.Command: string required oneof=run|check
+//	## Sections List
+//	.@min: 1
+//	### Section
+//	.InputDir: string required
+//
+// Attributes starting with @ apply to the enclosing block: @required, @min,
+// @max (number of child blocks of a list of blocks) and @strict.
+func (ctx *Context) ParseSchema(fileName string, r io.Reader) (*Schema, error) {
+	doc, err := ctx.ParseFile(fileName, r)
+	if err != nil {
+		return nil, err
+	}
+	var diags Diagnostics
+	root := schemaFromBlock(doc, doc.root, &diags)
+	if err := diags.Err(); err != nil {
+		return nil, err
+	}
+	return &Schema{Root: root}, nil
+}
+
+func schemaFromBlock(doc *Document, blk BlockNode, diags *Diagnostics) *BlockSchema {
+	bs := &BlockSchema{Name: blk.Value()}
+	for _, a := range blk.attribNodes() {
+		if err := bs.setProperty(a); err != nil {
+			diags.add(doc.path, a.LineNum(), SevError, err.Error())
+		}
+	}
+	for _, c := range blk.Children() {
+		if c.Kind() == LtBlock {
+			bs.Blocks = append(bs.Blocks, schemaFromBlock(doc, c.(BlockNode), diags))
+		}
+	}
+	return bs
+}
+
+// setProperty sets a block property (@key) or declares an attribute
+func (bs *BlockSchema) setProperty(a *ttAttrib) error {
+	if !strings.HasPrefix(a.key, "@") {
+		as, err := parseAttribSchema(a.key, a.value)
+		if err != nil {
+			return err
+		}
+		bs.Attribs = append(bs.Attribs, as)
+		return nil
+	}
+	var err error
+	switch key := trimLower(a.key[1:]); key {
+	case "required":
+		bs.Required, err = strconv.ParseBool(a.value)
+	case "strict":
+		bs.Strict, err = strconv.ParseBool(a.value)
+	case "min":
+		bs.MinItems, err = strconv.Atoi(a.value)
+	case "max":
+		bs.MaxItems, err = strconv.Atoi(a.value)
+	default:
+		return fmt.Errorf("unknown block property '@%s'", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for '%s': %s", a.key, a.value)
+	}
+	return nil
+}
+
+// parseAttribSchema parses an attribute declaration eg "string required oneof=a|b"
+func parseAttribSchema(name, spec string) (*AttribSchema, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("attribute '%s': missing type", name)
+	}
+	typ, err := ParseAttribType(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("attribute '%s': %s", name, err)
+	}
+	as := &AttribSchema{Name: name, Type: typ}
+	for _, opt := range fields[1:] {
+		if err := as.setOption(opt); err != nil {
+			return nil, fmt.Errorf("attribute '%s': %s", name, err)
+		}
+	}
+	return as, nil
+}
+
+// setOption sets one option of a declaration: required, oneof=a|b, min=n or max=n
+func (as *AttribSchema) setOption(opt string) error {
+	key, value, _ := strings.Cut(opt, "=")
+	var err error
+	switch trimLower(key) {
+	case "required":
+		as.Required = true
+	case "oneof":
+		as.Allowed = strings.Split(value, "|")
+	case "min":
+		as.MinItems, err = strconv.Atoi(value)
+	case "max":
+		as.MaxItems, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("unknown option '%s'", opt)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid option '%s'", opt)
+	}
+	return nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// SchemaFromStruct derives a schema from a struct or a pointer to a struct.
+// Struct fields become blocks, slices of structs become lists of blocks and
+// other exported fields become attributes. The mdson field tag sets the name
+// and options, eg `mdson:"command,required,oneof=run|check"`.
+func SchemaFromStruct(v interface{}) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("SchemaFromStruct: %v is not a struct", t)
+	}
+	root, err := blockSchemaOf("root", t, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Root: root}, nil
+}
+
+// blockSchemaOf derives the schema of a block from t; visiting holds the
+// struct types being derived so that recursive types are reported
+func blockSchemaOf(name string, t reflect.Type, visiting map[reflect.Type]bool) (*BlockSchema, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("SchemaFromStruct: recursive type %s", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	bs := &BlockSchema{Name: name}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("mdson"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		fname := f.Name
+		if tag[0] != "" {
+			fname = tag[0]
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != timeType:
+			child, err := blockSchemaOf(fname, ft, visiting)
+			if err != nil {
+				return nil, err
+			}
+			child.Required = hasOption(tag, "required")
+			bs.Blocks = append(bs.Blocks, child)
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			if !isArray(trimLower(fname)) {
+				fname += " List"
+			}
+			item, err := blockSchemaOf("item", ft.Elem(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			list := &BlockSchema{Name: fname, Blocks: []*BlockSchema{item}}
+			if hasOption(tag, "required") {
+				list.Required, list.MinItems = true, 1
+			}
+			bs.Blocks = append(bs.Blocks, list)
+		default:
+			typ, err := attribTypeOf(ft)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %s", t.Name(), f.Name, err)
+			}
+			as := &AttribSchema{Name: fname, Type: typ}
+			for _, opt := range tag[1:] {
+				if err := as.setOption(opt); err != nil {
+					return nil, fmt.Errorf("field %s.%s: %s", t.Name(), f.Name, err)
+				}
+			}
+			bs.Attribs = append(bs.Attribs, as)
+		}
+	}
+	return bs, nil
+}
+
+func hasOption(tag []string, opt string) bool {
+	for _, o := range tag[1:] {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func attribTypeOf(t reflect.Type) (AttribType, error) {
+	switch t {
+	case timeType:
+		return TypeTime, nil
+	case durationType:
+		return TypeDuration, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInt, nil
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat, nil
+	case reflect.Bool:
+		return TypeBool, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String {
+			return TypeList, nil
+		}
+	}
+	return TypeString, fmt.Errorf("unsupported field type %s", t)
+}
+
+// Validate checks doc against schema and returns all problems found
+// positioned at the offending lines
+func Validate(doc *Document, schema *Schema) Diagnostics {
+	v := validator{doc: doc}
+	v.block(doc.root, schema.Root)
+	v.diags.Sort()
+	return v.diags
+}
+
+type validator struct {
+	doc   *Document
+	diags Diagnostics
+}
+
+func (v *validator) report(n Node, sev Severity, format string, args ...interface{}) {
+	line := n.LineNum()
+	if line == 0 { // root block
+		line = 1
+	}
+	v.diags.add(v.doc.path, line, sev, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) unknown(bs *BlockSchema, n Node, what, name string) {
+	sev := SevWarning
+	if bs.Strict {
+		sev = SevError
+	}
+	v.report(n, sev, "unknown %s '%s' in block '%s'", what, strings.TrimSpace(name), bs.Name)
+}
+
+func (v *validator) block(blk BlockNode, bs *BlockSchema) {
+	// attributes and ~lists
+	seen := map[string]bool{}
+	for _, a := range blk.attribNodes() {
+		name := trimLower(a.key)
+		as := bs.attrib(name)
+		if as == nil {
+			v.unknown(bs, a, "attribute", a.key)
+			continue
+		}
+		seen[name] = true
+		var items []string
+		if as.Type == TypeList {
+			items = splitList(a.value)
+		}
+		v.attrib(a, as, a.value, items)
+	}
+	for _, c := range blk.Children() {
+		if c.Kind() != LtList {
+			continue
+		}
+		name := trimLower(c.Key())
+		as := bs.attrib(name)
+		if as == nil || as.Type != TypeList {
+			v.unknown(bs, c, "list", c.Value())
+			continue
+		}
+		seen[name] = true
+		items := []string{}
+		for _, item := range c.(BlockNode).Children() {
+			items = append(items, strings.TrimSpace(item.Value()))
+		}
+		v.attrib(c, as, strings.Join(items, ", "), items)
+	}
+	for _, as := range bs.Attribs {
+		if as.Required && !seen[trimLower(as.Name)] {
+			v.report(blk, SevError, "block '%s': missing required attribute '%s'", bs.Name, as.Name)
+		}
+	}
+	// child blocks
+	count := 0
+	seen = map[string]bool{}
+	for _, c := range blk.Children() {
+		if c.Kind() != LtBlock {
+			continue
+		}
+		count++
+		var cs *BlockSchema
+		if bs.isList() {
+			if len(bs.Blocks) > 0 {
+				cs = bs.Blocks[0]
+			}
+		} else {
+			cs = bs.block(c.Key())
+		}
+		if cs == nil {
+			v.unknown(bs, c, "block", c.Value())
+			continue
+		}
+		seen[trimLower(cs.Name)] = true
+		v.block(c.(BlockNode), cs)
+	}
+	if bs.isList() {
+		if bs.MinItems > 0 && count < bs.MinItems {
+			v.report(blk, SevError, "block '%s': has %d items; at least %d required", bs.Name, count, bs.MinItems)
+		}
+		if bs.MaxItems > 0 && count > bs.MaxItems {
+			v.report(blk, SevError, "block '%s': has %d items; at most %d allowed", bs.Name, count, bs.MaxItems)
+		}
+		return
+	}
+	for _, cs := range bs.Blocks {
+		if cs.Required && !seen[trimLower(cs.Name)] {
+			v.report(blk, SevError, "block '%s': missing required block '%s'", bs.Name, cs.Name)
+		}
+	}
+}
+
+// attrib checks the value of an attribute or the items of a ~list
+func (v *validator) attrib(n Node, as *AttribSchema, value string, items []string) {
+	if as.Type == TypeList {
+		if as.MinItems > 0 && len(items) < as.MinItems {
+			v.report(n, SevError, "attribute '%s': has %d items; at least %d required", as.Name, len(items), as.MinItems)
+		}
+		if as.MaxItems > 0 && len(items) > as.MaxItems {
+			v.report(n, SevError, "attribute '%s': has %d items; at most %d allowed", as.Name, len(items), as.MaxItems)
+		}
+		for _, item := range items {
+			v.allowed(n, as, item)
+		}
+		return
+	}
+	if _, err := coerce(value, as.Type); err != nil {
+		v.report(n, SevError, "attribute '%s': '%s' is not a valid %s", as.Name, value, as.Type)
+		return
+	}
+	v.allowed(n, as, value)
+}
+
+func (v *validator) allowed(n Node, as *AttribSchema, value string) {
+	if len(as.Allowed) == 0 {
+		return
+	}
+	for _, a := range as.Allowed {
+		if strings.EqualFold(a, strings.TrimSpace(value)) {
+			return
+		}
+	}
+	v.report(n, SevError, "attribute '%s': '%s' is not one of %s", as.Name, value, strings.Join(as.Allowed, ", "))
+}
@@ -0,0 +1,84 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestValidateMDSonSchema(t *testing.T) {
+	schema, err := ctx.ParseSchema("test/config.schema.mdson", nil)
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	doc, err := ctx.ParseFile("test/config.mdson", nil)
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	diags := Validate(doc, schema)
+	got := []string{}
+	for _, d := range diags {
+		got = append(got, d.Error())
+	}
+	tu.Equal(t, got, []string{
+		"test/config.mdson:5: attribute 'Command': 'convert' is not one of run, check",
+		"test/config.mdson:6: attribute 'Debug': 'two' is not a valid int",
+		"test/config.mdson:7: unknown attribute 'OverWriteOuputFile' in block 'Job'",
+		"test/config.mdson:16: block 'Section': missing required attribute 'InputDir'",
+	})
+}
+
+func TestValidateStructSchema(t *testing.T) {
+	type section struct {
+		InputDir string `mdson:",required"`
+	}
+	type config struct {
+		Title string `mdson:"title,required"`
+		Tags  []string
+		Job   struct {
+			Command  string `mdson:",oneof=run|check"`
+			Debug    int
+			Sections []section `mdson:",required"`
+		} `mdson:",required"`
+	}
+	schema, err := SchemaFromStruct(&config{})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	src := ".tags: a, b\n# Job\n.Command: run\n.Debug: 1\n## Sections List\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	diags := Validate(doc, schema)
+	tu.Equal(t, len(diags), 2)
+	if len(diags) == 2 {
+		tu.Equal(t, diags[0].Msg, "block 'root': missing required attribute 'title'")
+		tu.Equal(t, diags[1].Msg, "block 'Sections List': has 0 items; at least 1 required")
+	}
+}
+
+type recursiveNode struct {
+	Name     string
+	Children []recursiveNode
+}
+
+func TestSchemaFromRecursiveStruct(t *testing.T) {
+	type person struct {
+		Name   string
+		Parent *person
+	}
+	_, err := SchemaFromStruct(person{})
+	tu.Equal(t, err != nil && strings.Contains(err.Error(), "recursive type"), true)
+	_, err = SchemaFromStruct(&recursiveNode{})
+	tu.Equal(t, err != nil && strings.Contains(err.Error(), "recursive type"), true)
+	// the same type may appear in several fields
+	type address struct{ City string }
+	_, err = SchemaFromStruct(struct{ Home, Work address }{})
+	tu.Equal(t, err, nil)
+}
@@ -0,0 +1,275 @@
+// Package site builds a tree of MDSon documents, eg the sections and chapters
+// of a book or the pages of a web site, into an output tree rendered by an
+// mdson.Transformer.
+package site
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/drgo/mdson"
+)
+
+// SourceExt is the extension of the source files included in a build
+const SourceExt = mdson.SourceExt
+
+// Config controls a build
+type Config struct {
+	// root of the source tree
+	SourceDir string
+	// root of the output tree; created if needed
+	OutputDir string
+	// name of the output format in Formats, eg md
+	Format string
+	// output formats by name; mdson.DefaultFormats() if nil. Their options
+	// are set from ConfigFile (see mdson.FormatMap.Load)
+	Formats mdson.FormatMap
+	// include documents whose front matter sets .draft: true
+	Drafts bool
+	// maximum number of files parsed concurrently; defaults to the number of CPUs
+	Workers int
+	// parsing options; mdson.DefaultOptions() if nil
+	Options *mdson.Options
+	// front matter fields indexed as taxonomies, eg tags; read from
+	// ConfigFile if nil
+	Taxonomies []string
+}
+
+// Page is a source file of the site
+type Page struct {
+	// path of the source relative to Config.SourceDir using forward slashes
+	Path string
+	Doc  *mdson.Document
+}
+
+// Meta returns the front matter of the page
+func (p *Page) Meta() *mdson.Metadata {
+	return p.Doc.Metadata()
+}
+
+// Site holds the pages of a build in reading order
+type Site struct {
+	Config Config
+	Pages  []*Page
+	// taxonomies by lower-case name
+	Taxonomies map[string]*Taxonomy
+	ctx        *mdson.Context
+	// Config.Formats with the options set in ConfigFile
+	formats mdson.FormatMap
+	// all loaded pages including unpublished drafts by Page.Path
+	all map[string]*Page
+	// outputs of the generated taxonomy listings
+	listings map[string]bool
+	// set if Config.Taxonomies was read from ConfigFile
+	configured bool
+}
+
+// Build loads the site and renders all its pages
+func Build(cfg Config) (*Site, error) {
+	s, err := Load(cfg)
+	if err != nil {
+		return s, err
+	}
+	return s, s.Render()
+}
+
+// Load parses all source files under cfg.SourceDir concurrently, skipping
+// drafts unless cfg.Drafts is set, orders the pages (see Sort) and indexes
+// their taxonomies. All parsing errors are returned together with a Site
+// holding the pages that parsed.
+func Load(cfg Config) (*Site, error) {
+	if cfg.Format == "" {
+		cfg.Format = "md"
+	}
+	if cfg.Formats == nil {
+		cfg.Formats = mdson.DefaultFormats()
+	}
+	if _, err := cfg.Formats.Get(cfg.Format); err != nil {
+		return nil, err
+	}
+	opts := cfg.Options
+	if opts == nil {
+		opts = mdson.DefaultOptions()
+	}
+	s := &Site{
+		Config:   cfg,
+		ctx:      mdson.NewContext(opts),
+		all:      make(map[string]*Page),
+		listings: make(map[string]bool),
+	}
+	if err := s.loadConfig(); err != nil {
+		return nil, err
+	}
+	paths, err := sources(cfg.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.loadPages(paths)
+	for _, p := range pages {
+		if p != nil {
+			s.all[p.Path] = p
+		}
+	}
+	s.index()
+	return s, err
+}
+
+// loadPages parses the source files paths concurrently. The returned slice
+// holds the page of paths[i] at index i, or nil if it failed to parse.
+func (s *Site) loadPages(paths []string) ([]*Page, error) {
+	workers := s.Config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	pages := make([]*Page, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pages[i], errs[i] = s.loadPage(paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return pages, errors.Join(errs...)
+}
+
+// index selects the pages to publish, orders them and indexes their taxonomies
+func (s *Site) index() {
+	s.Pages = s.Pages[:0]
+	for _, p := range s.all {
+		if !p.Meta().Draft || s.Config.Drafts {
+			s.Pages = append(s.Pages, p)
+		}
+	}
+	Sort(s.Pages)
+	s.indexTaxonomies()
+}
+
+// sources returns the paths of all source files under dir relative to dir,
+// except ConfigFile
+func sources(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != SourceExt {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != ConfigFile {
+			paths = append(paths, rel)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func (s *Site) loadPage(rel string) (*Page, error) {
+	doc, err := s.ctx.ParseFile(filepath.Join(s.Config.SourceDir, filepath.FromSlash(rel)), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{Path: rel, Doc: doc}, nil
+}
+
+// Sort orders pages by directory so that the pages of a section stay
+// together, then by weight (lower first), date (newer first) and path.
+func Sort(pages []*Page) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		a, b := pages[i], pages[j]
+		if da, db := path.Dir(a.Path), path.Dir(b.Path); da != db {
+			return da < db
+		}
+		ma, mb := a.Meta(), b.Meta()
+		if ma.Weight != mb.Weight {
+			return ma.Weight < mb.Weight
+		}
+		if !ma.Date.Equal(mb.Date) {
+			return ma.Date.After(mb.Date)
+		}
+		return a.Path < b.Path
+	})
+}
+
+// OutputPath returns the path of the rendered page relative to Config.OutputDir
+func (s *Site) OutputPath(p *Page) string {
+	return strings.TrimSuffix(p.Path, SourceExt) + s.format().Ext
+}
+
+// Render writes every page and the taxonomy listings to the output tree
+func (s *Site) Render() error {
+	var errs []error
+	for _, p := range s.Pages {
+		_, err := s.renderPage(p)
+		errs = append(errs, err)
+	}
+	_, _, err := s.renderTaxonomies()
+	errs = append(errs, err)
+	return errors.Join(errs...)
+}
+
+// renderPage writes the output of p and reports whether it changed
+func (s *Site) renderPage(p *Page) (bool, error) {
+	t := s.format().Transformer()
+	return s.writeFile(s.OutputPath(p), func(w io.Writer) error {
+		return t.Transform(w, p.Doc)
+	})
+}
+
+// writeFile renders the output file rel with write. It leaves the file
+// untouched if its contents would not change and reports whether it wrote it.
+func (s *Site) writeFile(rel string, write func(w io.Writer) error) (bool, error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return false, fmt.Errorf("error rendering '%s': %s", rel, err)
+	}
+	name := filepath.Join(s.Config.OutputDir, filepath.FromSlash(rel))
+	if old, err := os.ReadFile(name); err == nil && bytes.Equal(old, buf.Bytes()) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeFile deletes the output file rel if it exists
+func (s *Site) removeFile(rel string) error {
+	err := os.Remove(filepath.Join(s.Config.OutputDir, filepath.FromSlash(rel)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// format returns the output format of the site; Load checked it exists
+func (s *Site) format() *mdson.OutputFormat {
+	f, _ := s.formats.Get(s.Config.Format)
+	return f
+}
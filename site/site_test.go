@@ -0,0 +1,179 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+	"github.com/drgo/mdson"
+)
+
+// writeTree creates files under dir from a map of slash-separated paths to contents
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testOptions() *mdson.Options {
+	return mdson.DefaultOptions().SetDebug(ui.DebugSilent)
+}
+
+var testTree = map[string]string{
+	"intro.mdson":          ".title: Intro\n.weight: 1\n# Intro\nWelcome\n",
+	"about.mdson":          ".title: About\n.weight: 2\n# About\n",
+	"notes.txt":            "not a source",
+	"chapters/one.mdson":   ".title: One\n.date: 2023-01-01\n# One\n",
+	"chapters/two.mdson":   ".title: Two\n.date: 2023-02-01\n# Two\n",
+	"chapters/draft.mdson": ".title: Draft\n.draft: true\n# Draft\n",
+}
+
+func TestBuild(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeTree(t, src, testTree)
+	s, err := Build(Config{SourceDir: src, OutputDir: out, Options: testOptions()})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	paths := []string{}
+	for _, p := range s.Pages {
+		paths = append(paths, p.Path)
+	}
+	tu.Equal(t, paths, []string{"intro.mdson", "about.mdson", "chapters/two.mdson", "chapters/one.mdson"})
+	b, err := os.ReadFile(filepath.Join(out, "intro.md"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "Welcome"), true)
+	_, err = os.Stat(filepath.Join(out, "chapters", "draft.md"))
+	tu.Equal(t, os.IsNotExist(err), true)
+
+	s, err = Load(Config{SourceDir: src, Drafts: true, Options: testOptions()})
+	tu.Equal(t, err, nil)
+	if err == nil {
+		tu.Equal(t, len(s.Pages), 5)
+	}
+}
+
+func TestBuildFormatOptions(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{
+		ConfigFile:    "# html\n.number headings: true\n.line ending: lf\n",
+		"intro.mdson": "# Intro\n## Scope\n",
+	})
+	_, err := Build(Config{SourceDir: src, OutputDir: out, Format: "html", Options: testOptions()})
+	tu.Equal(t, err, nil)
+	b, err := os.ReadFile(filepath.Join(out, "intro.html"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "<h1>1 Intro</h1>\n"), true)
+	tu.Equal(t, strings.Contains(string(b), "<h2>1.1 Scope</h2>\n"), true)
+}
+
+func TestBuildErrors(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"a.mdson": "##bad\n",
+		"b.mdson": ". : no key\n",
+	})
+	_, err := Load(Config{SourceDir: src, Options: testOptions()})
+	tu.Equal(t, err != nil, true)
+	if err != nil {
+		tu.Equal(t, strings.Contains(err.Error(), "a.mdson:1"), true)
+		tu.Equal(t, strings.Contains(err.Error(), "b.mdson:1"), true)
+	}
+	_, err = Load(Config{SourceDir: src, Format: "pdf"})
+	tu.Equal(t, err.Error(), "unsupported output format 'pdf'")
+}
+
+func TestTaxonomies(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{
+		ConfigFile:    ".taxonomies: tags, categories\n",
+		"a.mdson":     ".title: Salmonella\n.tags: health, Food safety\n.categories: infections\n# A\n",
+		"b.mdson":     ".title: Noise\n.weight: 1\n~tags\n- Health\n- environment\n# B\n",
+		"c/d.mdson":   ".title: Draft\n.draft: true\n.tags: health\n# D\n",
+		"c/e.mdson":   ".title: JSON \\{ \\}\n.tags: environment\n# E\n",
+		"c/f.mdson":   "# F\n",
+		"c/g.mdson":   ".categories: infections, outbreaks\n# G\n",
+		"notes.mdson": "# Notes\n",
+	})
+	s, err := Build(Config{SourceDir: src, OutputDir: out, Options: testOptions()})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	tags := s.Taxonomy("Tags")
+	names := []string{}
+	for _, term := range tags.Terms() {
+		names = append(names, term.Name)
+	}
+	tu.Equal(t, names, []string{"environment", "Food safety", "health"})
+	pages := []string{}
+	for _, p := range tags.Pages("HEALTH") {
+		pages = append(pages, p.Path)
+	}
+	tu.Equal(t, pages, []string{"a.mdson", "b.mdson"})
+	tu.Equal(t, len(s.Taxonomy("categories").Pages("infections")), 2)
+	tu.Equal(t, s.Taxonomy("authors"), (*Taxonomy)(nil))
+
+	b, err := os.ReadFile(filepath.Join(out, "tags", "health.md"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "- [Noise](../b.md)"), true)
+	b, err = os.ReadFile(filepath.Join(out, "tags", "environment.md"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "- [JSON { }](../c/e.md)"), true)
+	b, err = os.ReadFile(filepath.Join(out, "tags", "index.md"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "- [Food safety](food-safety.md) (1)"), true)
+	_, err = os.Stat(filepath.Join(out, "config.md"))
+	tu.Equal(t, os.IsNotExist(err), true)
+}
+
+func TestTaxonomySlugs(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{
+		ConfigFile: ".taxonomies: tags\n",
+		"a.mdson":  ".title: A\n.tags: C, C++, Index\n# A\n",
+	})
+	s, err := Build(Config{SourceDir: src, OutputDir: out, Options: testOptions()})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	slugs := []string{}
+	for _, term := range s.Taxonomy("tags").Terms() {
+		slugs = append(slugs, term.Slug())
+	}
+	tu.Equal(t, slugs, []string{"c", "c-2", "index-2"})
+	b, err := os.ReadFile(filepath.Join(out, "tags", "index.md"))
+	tu.Equal(t, err, nil)
+	tu.Equal(t, strings.Contains(string(b), "- [C++](c-2.md) (1)"), true)
+	tu.Equal(t, strings.Contains(string(b), "- [Index](index-2.md) (1)"), true)
+	_, err = os.Stat(filepath.Join(out, "tags", "index-2.md"))
+	tu.Equal(t, err, nil)
+}
+
+func TestTaxonomyPageCollisions(t *testing.T) {
+	for _, page := range []string{"tags/index.mdson", "tags/health.mdson"} {
+		src, out := t.TempDir(), t.TempDir()
+		writeTree(t, src, map[string]string{
+			ConfigFile: ".taxonomies: tags\n",
+			"a.mdson":  ".title: A\n.tags: health\n# A\n",
+			page:       "# Real page\n",
+		})
+		_, err := Build(Config{SourceDir: src, OutputDir: out, Options: testOptions()})
+		tu.Equal(t, err != nil && strings.Contains(err.Error(), "would overwrite the output of "+page), true)
+		b, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(strings.TrimSuffix(page, ".mdson")+".md")))
+		tu.Equal(t, err, nil)
+		tu.Equal(t, strings.Contains(string(b), "Real page"), true)
+	}
+}
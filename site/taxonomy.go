@@ -0,0 +1,278 @@
+package site
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigFile is the name of the optional site configuration file at the root
+// of the source tree. It is not rendered as a page. It declares the
+// taxonomies of the site and the options of output formats in blocks named
+// after them (see mdson.FormatMap.Load), eg
+//
+//	.taxonomies: tags, categories
+//	# html
+//	.number headings: true
+const ConfigFile = "config" + SourceExt
+
+// Taxonomy indexes the pages of a site by the terms they list under a front
+// matter field, eg tags. Terms are matched case-insensitively; the spelling
+// first seen in reading order is kept for display.
+type Taxonomy struct {
+	Name  string
+	terms map[string]*Term
+}
+
+// Term is a taxonomy term and the pages that list it, in reading order
+type Term struct {
+	Name  string
+	Pages []*Page
+	slug  string
+}
+
+// Slug returns the term's name in a form usable as a file name. Slugs are
+// unique within a taxonomy and never "index", the name of its index page:
+// terms whose names give the same slug, eg C and C++, are numbered (c, c-2)
+// in the order of Terms.
+func (t *Term) Slug() string {
+	if t.slug == "" {
+		return slug(t.Name)
+	}
+	return t.slug
+}
+
+// Terms returns the terms of the taxonomy sorted by name
+func (tx *Taxonomy) Terms() []*Term {
+	terms := make([]*Term, 0, len(tx.terms))
+	for _, t := range tx.terms {
+		terms = append(terms, t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i].Name) < strings.ToLower(terms[j].Name)
+	})
+	return terms
+}
+
+// Term returns the term called name or nil if no page lists it
+func (tx *Taxonomy) Term(name string) *Term {
+	return tx.terms[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// Pages returns the pages listing term
+func (tx *Taxonomy) Pages(term string) []*Page {
+	if t := tx.Term(term); t != nil {
+		return t.Pages
+	}
+	return nil
+}
+
+// assignSlugs gives each term a unique slug
+func (tx *Taxonomy) assignSlugs() {
+	taken := map[string]bool{"index": true}
+	for _, t := range tx.Terms() {
+		base := slug(t.Name)
+		s := base
+		for i := 2; taken[s]; i++ {
+			s = base + "-" + strconv.Itoa(i)
+		}
+		taken[s] = true
+		t.slug = s
+	}
+}
+
+func (tx *Taxonomy) add(term string, p *Page) {
+	key := strings.ToLower(term)
+	t := tx.terms[key]
+	if t == nil {
+		t = &Term{Name: term}
+		tx.terms[key] = t
+	}
+	if n := len(t.Pages); n == 0 || t.Pages[n-1] != p {
+		t.Pages = append(t.Pages, p)
+	}
+}
+
+// Taxonomy returns the taxonomy called name or nil if the site does not declare it
+func (s *Site) Taxonomy(name string) *Taxonomy {
+	return s.Taxonomies[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// loadConfig reads the options of the output formats declared in ConfigFile
+// and its taxonomies unless Config.Taxonomies was set by the caller
+func (s *Site) loadConfig() error {
+	s.formats = s.Config.Formats.Clone()
+	taxonomies := s.Config.Taxonomies == nil || s.configured
+	if taxonomies {
+		s.Config.Taxonomies = nil
+	}
+	name := filepath.Join(s.Config.SourceDir, ConfigFile)
+	if _, err := os.Stat(name); os.IsNotExist(err) {
+		return nil
+	}
+	doc, err := s.ctx.ParseFile(name, nil)
+	if err != nil {
+		return err
+	}
+	if taxonomies {
+		s.Config.Taxonomies = splitTerms(doc.Metadata().Extra["taxonomies"])
+		s.configured = true
+	}
+	if err := s.formats.Load(doc); err != nil {
+		return fmt.Errorf("%s: %s", name, err)
+	}
+	return nil
+}
+
+// indexTaxonomies builds the index of every configured taxonomy
+func (s *Site) indexTaxonomies() {
+	s.Taxonomies = make(map[string]*Taxonomy)
+	for _, name := range s.Config.Taxonomies {
+		tx := &Taxonomy{Name: name, terms: make(map[string]*Term)}
+		for _, p := range s.Pages {
+			for _, term := range pageTerms(p, name) {
+				tx.add(term, p)
+			}
+		}
+		tx.assignSlugs()
+		s.Taxonomies[strings.ToLower(name)] = tx
+	}
+}
+
+// pageTerms returns the terms listed by p under the front matter field taxonomy
+func pageTerms(p *Page, taxonomy string) []string {
+	m := p.Meta()
+	switch strings.ToLower(taxonomy) {
+	case "tags":
+		return m.Tags
+	case "authors":
+		return m.Authors
+	case "type":
+		return splitTerms(m.Type)
+	}
+	return splitTerms(m.Extra[strings.ToLower(taxonomy)])
+}
+
+// renderTaxonomies writes, for each taxonomy, an index page listing its terms
+// (eg tags/index.md) and a page per term listing its pages (eg tags/health.md).
+// Listings are generated as MDSon and rendered by the site's Transformer.
+// Listings of terms no longer used are removed. It returns the outputs
+// written and removed, or an error without writing anything if a listing
+// would overwrite the output of a page, eg that of tags/index.mdson.
+func (s *Site) renderTaxonomies() (written, removed []string, err error) {
+	type listing struct{ name, src string }
+	var generated []listing
+	add := func(name, src string) {
+		generated = append(generated, listing{name, src})
+	}
+	for _, name := range s.Config.Taxonomies {
+		tx := s.Taxonomy(name)
+		dir := slug(tx.Name)
+		var sb strings.Builder
+		fmt.Fprintf(&sb, ".title: %s\n# %s\n", escape(tx.Name), escape(tx.Name))
+		for _, t := range tx.Terms() {
+			fmt.Fprintf(&sb, "\\- [%s](%s) (%d)\n", escape(t.Name), s.outputName(t.Slug()), len(t.Pages))
+		}
+		add(path.Join(dir, "index"), sb.String())
+		for _, t := range tx.Terms() {
+			sb.Reset()
+			fmt.Fprintf(&sb, ".title: %s: %s\n# %s\n", escape(tx.Name), escape(t.Name), escape(t.Name))
+			for _, p := range t.Pages {
+				title := p.Meta().Title
+				if title == "" {
+					title = p.Path
+				}
+				fmt.Fprintf(&sb, "\\- [%s](../%s)\n", escape(title), s.OutputPath(p))
+			}
+			add(path.Join(dir, t.Slug()), sb.String())
+		}
+	}
+	pages := make(map[string]*Page, len(s.Pages))
+	for _, p := range s.Pages {
+		pages[s.OutputPath(p)] = p
+	}
+	for _, l := range generated {
+		if p := pages[s.outputName(l.name)]; p != nil {
+			return nil, nil, fmt.Errorf("taxonomy listing %s would overwrite the output of %s", s.outputName(l.name), p.Path)
+		}
+	}
+	listings := make(map[string]bool)
+	for _, l := range generated {
+		out := s.outputName(l.name)
+		listings[out] = true
+		ok, err := s.renderListing(l.name, l.src)
+		if ok {
+			written = append(written, out)
+		}
+		if err != nil {
+			return written, removed, err
+		}
+	}
+	for out := range s.listings {
+		if !listings[out] {
+			if err := s.removeFile(out); err != nil {
+				return written, removed, err
+			}
+			removed = append(removed, out)
+		}
+	}
+	s.listings = listings
+	return written, removed, nil
+}
+
+// renderListing parses the generated MDSon src and writes it to name (without
+// extension); it reports whether the output changed
+func (s *Site) renderListing(name, src string) (bool, error) {
+	doc, err := s.ctx.ParseFile(name+SourceExt, strings.NewReader(src))
+	if err != nil {
+		return false, fmt.Errorf("error generating '%s': %s", name, err)
+	}
+	return s.renderPage(&Page{Path: name + SourceExt, Doc: doc})
+}
+
+// outputName returns name with the extension of the site's output format
+func (s *Site) outputName(name string) string {
+	return name + s.format().Ext
+}
+
+// splitTerms splits a comma-separated list into its trimmed non-empty items
+func splitTerms(s string) []string {
+	terms := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// slug lower-cases s and replaces runs of characters other than letters and
+// digits with a single hyphen
+func slug(s string) string {
+	var sb strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127 {
+			if hyphen && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// escape protects braces in generated MDSon text from reference expansion
+func escape(s string) string {
+	return strings.NewReplacer("{", `\{`, "}", `\}`).Replace(s)
+}
@@ -0,0 +1,172 @@
+package site
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/drgo/mdson"
+	"github.com/drgo/mdson/watch"
+)
+
+// Rebuild reports the outcome of an incremental build
+type Rebuild struct {
+	// source files created, modified or removed
+	Changed []string
+	// pages parsed again, by Page.Path
+	Parsed []string
+	// output files written or removed, relative to Config.OutputDir, sorted
+	Written []string
+	Removed []string
+	// warnings reported while parsing the pages
+	Warnings mdson.Diagnostics
+	// parsing and rendering errors; pages that fail to parse keep their
+	// previous version and output
+	Err error
+}
+
+// Update rebuilds the site after the source files changed were created,
+// modified or removed. Only the pages whose sources (see
+// mdson.Document.Sources) changed are parsed again and only outputs whose
+// contents change are rewritten. A change to ConfigFile reloads every page.
+// Update must not be called concurrently with other methods of s.
+func (s *Site) Update(changed []string) *Rebuild {
+	r := &Rebuild{Changed: changed}
+	var errs []error
+	isChanged := make(map[string]bool)
+	for _, f := range changed {
+		isChanged[filepath.Clean(f)] = true
+	}
+	reloadAll := isChanged[filepath.Join(s.Config.SourceDir, ConfigFile)]
+	if reloadAll {
+		errs = append(errs, s.loadConfig())
+	}
+	paths, err := sources(s.Config.SourceDir)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	published := make(map[string]bool)
+	for _, p := range s.Pages {
+		published[p.Path] = true
+	}
+	exists := make(map[string]bool)
+	var reload []string
+	for _, rel := range paths {
+		exists[rel] = true
+		if p := s.all[rel]; p == nil || reloadAll || dependsOn(p, isChanged) {
+			reload = append(reload, rel)
+		}
+	}
+	for rel := range s.all {
+		if !exists[rel] {
+			delete(s.all, rel)
+		}
+	}
+	pages, err := s.loadPages(reload)
+	errs = append(errs, err)
+	for _, p := range pages {
+		if p != nil {
+			s.all[p.Path] = p
+			r.Parsed = append(r.Parsed, p.Path)
+			r.Warnings = append(r.Warnings, p.Doc.Diagnostics()...)
+		}
+	}
+	s.index()
+	for _, p := range s.Pages {
+		delete(published, p.Path)
+	}
+	for _, p := range pages {
+		if p == nil || (p.Meta().Draft && !s.Config.Drafts) {
+			continue
+		}
+		ok, err := s.renderPage(p)
+		if ok {
+			r.Written = append(r.Written, s.OutputPath(p))
+		}
+		errs = append(errs, err)
+	}
+	// pages no longer published because they were removed or made drafts
+	for rel := range published {
+		out := s.OutputPath(&Page{Path: rel})
+		if err := s.removeFile(out); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r.Removed = append(r.Removed, out)
+	}
+	written, removed, err := s.renderTaxonomies()
+	r.Written = append(r.Written, written...)
+	r.Removed = append(r.Removed, removed...)
+	errs = append(errs, err)
+	sort.Strings(r.Written)
+	sort.Strings(r.Removed)
+	r.Err = errors.Join(errs...)
+	return r
+}
+
+// dependsOn reports whether any source of p is in files
+func dependsOn(p *Page, files map[string]bool) bool {
+	for _, f := range p.Doc.Sources() {
+		if files[filepath.Clean(f)] {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceFiles returns the files whose changes affect the site
+func (s *Site) sourceFiles() []string {
+	files := []string{filepath.Join(s.Config.SourceDir, ConfigFile)}
+	for _, p := range s.all {
+		files = append(files, p.Doc.Sources()...)
+	}
+	// including those that failed to parse
+	paths, _ := sources(s.Config.SourceDir)
+	for _, rel := range paths {
+		files = append(files, filepath.Join(s.Config.SourceDir, filepath.FromSlash(rel)))
+	}
+	return files
+}
+
+// Watch checks the source tree for changes every interval (or
+// watch.DefaultInterval if interval <= 0) and calls Update when some are
+// found, passing its outcome to report. It returns when ctx is done.
+func (s *Site) Watch(ctx context.Context, interval time.Duration, report func(*Rebuild)) {
+	w := watch.New(interval)
+	w.Add(s.sourceFiles()...)
+	if interval <= 0 {
+		interval = watch.DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed := w.Poll()
+		// sources created since the last poll
+		if paths, err := sources(s.Config.SourceDir); err == nil {
+			watched := make(map[string]bool)
+			for _, f := range w.Files() {
+				watched[f] = true
+			}
+			for _, rel := range paths {
+				if name := filepath.Join(s.Config.SourceDir, filepath.FromSlash(rel)); !watched[name] {
+					changed = append(changed, name)
+					w.Add(name)
+				}
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		r := s.Update(changed)
+		w.Add(s.sourceFiles()...)
+		report(r)
+	}
+}
@@ -0,0 +1,85 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestUpdate(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	writeTree(t, src, map[string]string{
+		ConfigFile:  ".taxonomies: tags\n",
+		"a.mdson":   ".title: A\n.tags: x\n# A\n",
+		"b.mdson":   ".title: B\n# B\n",
+		"c/d.mdson": ".title: D\n.tags: y\n# D\n",
+	})
+	s, err := Build(Config{SourceDir: src, OutputDir: out, Options: testOptions()})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	name := func(rel string) string { return filepath.Join(src, filepath.FromSlash(rel)) }
+
+	// editing a page's text rewrites only its output
+	writeTree(t, src, map[string]string{"b.mdson": ".title: B\n# B\nmore\n"})
+	r := s.Update([]string{name("b.mdson")})
+	tu.Equal(t, r.Err, nil)
+	tu.Equal(t, r.Parsed, []string{"b.mdson"})
+	tu.Equal(t, r.Written, []string{"b.md"})
+
+	// a new tag adds a listing and updates the index; front matter is not
+	// rendered so b.md is unchanged
+	writeTree(t, src, map[string]string{"b.mdson": ".title: B\n.tags: z\n# B\nmore\n"})
+	r = s.Update([]string{name("b.mdson")})
+	tu.Equal(t, r.Written, []string{"tags/index.md", "tags/z.md"})
+
+	// a page made a draft loses its output and its terms
+	writeTree(t, src, map[string]string{"c/d.mdson": ".title: D\n.tags: y\n.draft: true\n# D\n"})
+	r = s.Update([]string{name("c/d.mdson")})
+	tu.Equal(t, r.Written, []string{"tags/index.md"})
+	tu.Equal(t, r.Removed, []string{"c/d.md", "tags/y.md"})
+	_, err = os.Stat(filepath.Join(out, "c", "d.md"))
+	tu.Equal(t, os.IsNotExist(err), true)
+
+	// a page that fails to parse keeps its previous output
+	writeTree(t, src, map[string]string{"a.mdson": "##bad\n", "e.mdson": "# E\n"})
+	r = s.Update([]string{name("a.mdson"), name("e.mdson")})
+	tu.Equal(t, r.Err != nil, true)
+	tu.Equal(t, r.Parsed, []string{"e.mdson"})
+	tu.Equal(t, r.Written, []string{"e.md"})
+	tu.Equal(t, s.all["a.mdson"].Meta().Title, "A")
+
+	os.Remove(name("a.mdson"))
+	r = s.Update([]string{name("a.mdson")})
+	tu.Equal(t, r.Err, nil)
+	tu.Equal(t, r.Removed, []string{"a.md", "tags/x.md"})
+	tu.Equal(t, len(s.Pages), 2)
+}
+
+func TestUpdateDependencies(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	defaults := filepath.Join(t.TempDir(), "defaults.mdson")
+	os.WriteFile(defaults, []byte(".version: 1\n"), 0o644)
+	writeTree(t, src, map[string]string{
+		"a.mdson": "# A\nversion {version}\n",
+		"b.mdson": "# B\n",
+	})
+	opts := testOptions()
+	opts.DefaultsFile = defaults
+	s, err := Build(Config{SourceDir: src, OutputDir: out, Options: opts})
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	// the defaults file feeds every page, but only a's output changes
+	os.WriteFile(defaults, []byte(".version: 2\n"), 0o644)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(defaults, later, later)
+	r := s.Update([]string{defaults})
+	tu.Equal(t, r.Parsed, []string{"a.mdson", "b.mdson"})
+	tu.Equal(t, r.Written, []string{"a.md"})
+}
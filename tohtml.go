@@ -0,0 +1,151 @@
+package mdson
+
+import (
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var _ Transformer = &HTMLTransformer{}
+
+// HTMLTransformer renders a document as an HTML page. Blocks become sections
+// with a heading, ~lists become unordered lists and consecutive text lines
+// become paragraphs. Text is escaped; Markdown inline markup is not
+// interpreted. Attributes and comments are not rendered.
+type HTMLTransformer struct {
+	printer
+	TransformerConfig
+	// if set, only the body's content is written
+	Fragment bool
+	// markup written at the end of the body, eg a script
+	Footer string
+	// true while a <p> element is open
+	inPara  bool
+	numbers headingNumbers
+}
+
+// NewHTMLTransformer returns a Transformer that produces HTML
+func NewHTMLTransformer(cfg TransformerConfig) *HTMLTransformer {
+	return &HTMLTransformer{TransformerConfig: cfg}
+}
+
+func (h *HTMLTransformer) Transform(w io.Writer, doc *Document) error {
+	if err := h.checkNames(); err != nil {
+		return err
+	}
+	h.printer = printer{w: w, format: "html", plugins: doc.plugins(), eol: h.lineEnding(), hookErr: new(error)}
+	h.numbers = headingNumbers{}
+	h.inPara = false
+	if !h.Fragment {
+		h.println("<!DOCTYPE html>")
+		h.println("<html>")
+		h.println("<head>")
+		h.println(`<meta charset="utf-8">`)
+		if title := htmlTitle(doc); title != "" {
+			h.println("<title>" + html.EscapeString(title) + "</title>")
+		}
+		if style := h.style(); style != "" {
+			h.println("<style>body { " + style + " }</style>")
+		}
+		h.println("</head>")
+		h.println("<body>")
+	}
+	h.printNode(doc.root)
+	h.closePara()
+	if h.Footer != "" {
+		h.println(h.Footer)
+	}
+	if !h.Fragment {
+		h.println("</body>")
+		h.println("</html>")
+	}
+	return h.renderErr()
+}
+
+// htmlTitle returns the front matter title or else the name of the first block
+func htmlTitle(doc *Document) string {
+	if doc.meta != nil && doc.meta.Title != "" {
+		return doc.meta.Title
+	}
+	for _, n := range doc.root.Children() {
+		if n.Kind() == LtBlock {
+			return strings.TrimSpace(n.Value())
+		}
+	}
+	return ""
+}
+
+// style returns the CSS declarations setting the font of the body if any
+func (h *HTMLTransformer) style() string {
+	var decls []string
+	if h.Font != "" {
+		decls = append(decls, "font-family: "+strconv.Quote(h.Font)+";")
+	}
+	if h.FontSize > 0 {
+		decls = append(decls, "font-size: "+strconv.Itoa(h.FontSize)+"pt;")
+	}
+	return strings.Join(decls, " ")
+}
+
+func (h *HTMLTransformer) closePara() {
+	if h.inPara {
+		h.println("</p>")
+		h.inPara = false
+	}
+}
+
+func (h *HTMLTransformer) printNode(n Node) {
+	pre := func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ttBlock:
+			h.closePara()
+			if n.Level() > 0 { // donot print root's title
+				level := n.Level()
+				if level > 6 {
+					level = 6
+				}
+				tag := "h" + strconv.Itoa(level)
+				h.println(`<section id="` + html.EscapeString(anchor(n.Key())) + `">`)
+				h.println("<" + tag + ">" + html.EscapeString(h.numbers.next(n.Level(), h.NumberHeadings)+strings.TrimSpace(n.Value())) + "</" + tag + ">")
+			}
+		case *ttList:
+			h.closePara()
+			h.println("<ul>")
+		case *ttListItem:
+			h.println("<li>" + html.EscapeString(strings.TrimSpace(n.Value())) + "</li>")
+		case *ttTextLine:
+			if !h.inPara {
+				h.print("<p>")
+				h.inPara = true
+			} else {
+				h.println("")
+			}
+			h.print(html.EscapeString(n.Value()))
+		case *ttEmpty:
+			h.closePara()
+		case Custom:
+			h.closePara()
+			h.custom(n)
+		}
+		return true
+	}
+	post := func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ttBlock:
+			h.closePara()
+			if n.Level() > 0 {
+				h.println("</section>")
+			}
+		case *ttList:
+			h.println("</ul>")
+		}
+		return true
+	}
+	Apply(n, pre, post)
+}
+
+// anchor returns an HTML id for a block key by replacing spaces with hyphens
+func anchor(key string) string {
+	return strings.Join(strings.Fields(key), "-")
+}
@@ -0,0 +1,43 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestTransformHTML(t *testing.T) {
+	src := ".title: Notes & Queries\n# Intro\nfirst line\nsecond <line>\n\nnew para\n~ items\n- one\n- two\n## Sub Part\ntext\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	h := NewHTMLTransformer(DefaultTransformerConfig())
+	h.Fragment = true
+	var sb strings.Builder
+	h.Transform(&sb, doc)
+	want := []string{
+		`<section id="intro">`,
+		"<h1>Intro</h1>",
+		"<p>first line",
+		"second &lt;line&gt;</p>",
+		"<p>new para</p>",
+		"<ul>",
+		"<li>one</li>",
+		"<li>two</li>",
+		"</ul>",
+		`<section id="sub-part">`,
+		"<h2>Sub Part</h2>",
+		"<p>text</p>",
+		"</section>",
+		"</section>",
+		"",
+	}
+	tu.Equal(t, sb.String(), strings.Join(want, EOL))
+	sb.Reset()
+	h.Fragment = false
+	h.Transform(&sb, doc)
+	tu.Equal(t, strings.Contains(sb.String(), "<title>Notes &amp; Queries</title>"), true)
+}
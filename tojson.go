@@ -0,0 +1,98 @@
+package mdson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+var _ Transformer = &JSONTransformer{}
+
+// JSONTransformer writes the evaluated document tree as JSON, eg
+//
+//	{"attribs": {"title": "CHF"}, "children": [
+//	  {"type": "block", "name": "Introduction", "level": 1, "children": [
+//	    {"type": "text", "value": "some text"},
+//	    {"type": "list", "name": "Causes", "items": ["ischemia", "valves"]}]}]}
+//
+// Attributes are keyed by their name as written. Empty lines and comments
+// are omitted. Nodes produced by plugins are written as "custom" nodes with
+// the output of their json render hook, if any, as "data".
+type JSONTransformer struct {
+	TransformerConfig
+}
+
+// NewJSONTransformer returns a Transformer that produces JSON
+func NewJSONTransformer(cfg TransformerConfig) *JSONTransformer {
+	return &JSONTransformer{TransformerConfig: cfg}
+}
+
+// jsonNode is the JSON representation of a Node
+type jsonNode struct {
+	Type     string            `json:"type,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Level    int               `json:"level,omitempty"`
+	Value    *string           `json:"value,omitempty"`
+	Attribs  map[string]string `json:"attribs,omitempty"`
+	Items    []string          `json:"items,omitempty"`
+	Data     json.RawMessage   `json:"data,omitempty"`
+	Children []*jsonNode       `json:"children,omitempty"`
+}
+
+func (j *JSONTransformer) Transform(w io.Writer, doc *Document) error {
+	enc := json.NewEncoder(w)
+	if j.TabWidth > 0 {
+		enc.SetIndent("", strings.Repeat(" ", j.TabWidth))
+	}
+	p := printer{format: "json", plugins: doc.plugins(), hookErr: new(error)}
+	root := toJSON(doc.root, p)
+	if err := p.renderErr(); err != nil {
+		return err
+	}
+	root.Type, root.Name = "", ""
+	return enc.Encode(root)
+}
+
+// toJSON converts n to a jsonNode or returns nil for nodes that are omitted;
+// p renders custom nodes
+func toJSON(n Node, p printer) *jsonNode {
+	switch n := n.(type) {
+	case *ttBlock:
+		jn := &jsonNode{Type: "block", Name: strings.TrimSpace(n.Value()), Level: n.Level()}
+		for _, a := range n.attribNodes() {
+			if jn.Attribs == nil {
+				jn.Attribs = make(map[string]string)
+			}
+			jn.Attribs[a.key] = a.value
+		}
+		for _, c := range n.Children() {
+			if jc := toJSON(c, p); jc != nil {
+				jn.Children = append(jn.Children, jc)
+			}
+		}
+		return jn
+	case *ttList:
+		jn := &jsonNode{Type: "list", Name: strings.TrimSpace(n.Value()), Items: []string{}}
+		for _, c := range n.Children() {
+			if c.Kind() == LtListItem {
+				jn.Items = append(jn.Items, strings.TrimSpace(c.Value()))
+			}
+		}
+		return jn
+	case *ttTextLine:
+		value := n.Value()
+		return &jsonNode{Type: "text", Value: &value}
+	case Custom:
+		d := n.Directive()
+		args := d.Args
+		jn := &jsonNode{Type: "custom", Name: d.Name, Value: &args, Items: d.Body}
+		var buf bytes.Buffer
+		p.w = &buf
+		if p.custom(n) {
+			jn.Data = json.RawMessage(bytes.TrimSpace(buf.Bytes()))
+		}
+		return jn
+	}
+	return nil
+}
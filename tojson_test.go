@@ -0,0 +1,25 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestTransformJSON(t *testing.T) {
+	src := ".Title: CHF\n# Introduction\n.Status: draft\nsome text\n\n~ Causes\n- ischemia\n- valves\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(src))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	var sb strings.Builder
+	cfg := DefaultTransformerConfig()
+	cfg.TabWidth = 0
+	NewJSONTransformer(cfg).Transform(&sb, doc)
+	tu.Equal(t, sb.String(), `{"attribs":{"Title":"CHF"},"children":[`+
+		`{"type":"block","name":"Introduction","level":1,"attribs":{"Status":"draft"},"children":[`+
+		`{"type":"text","value":"some text"},`+
+		`{"type":"list","name":"Causes","items":["ischemia","valves"]}]}]}`+"\n")
+}
@@ -3,7 +3,9 @@ package mdson
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"unicode"
 )
 
 const EOL = "\r\n"
@@ -12,8 +14,16 @@ type Transformer interface {
 	Transform(w io.Writer, d *Document) error
 }
 
-type printer struct{
+type printer struct {
 	w io.Writer
+	// output format and plugins used to render custom nodes (see custom())
+	format  string
+	plugins []*Plugin
+	// written after each line; EOL if empty
+	eol string
+	// the first error returned by a render hook; shared by copies of the
+	// printer (see custom())
+	hookErr *error
 }
 
 func (p printer) print(s string) {
@@ -21,14 +31,77 @@ func (p printer) print(s string) {
 }
 
 func (p printer) println(s string) {
-	fmt.Fprint(p.w, s, EOL)
+	eol := p.eol
+	if eol == "" {
+		eol = EOL
+	}
+	fmt.Fprint(p.w, s, eol)
+}
+
+//TransformerConfig holds basic output control vars and the options of the
+// output formats (see FormatMap); options are ignored by formats that do not
+// support them. The mdson tags name them in configuration files.
+type TransformerConfig struct {
+	Indent    string `mdson:"-"`
+	ListMaker string `mdson:"list marker"`
+	TabWidth  int    `mdson:"tab width"`
+	// lf or crlf; crlf if empty
+	LineEnding string `mdson:"line ending,oneof=lf|crlf"`
+	// paper size of paged formats (latex and mom), eg a4 or letter
+	PageSize string `mdson:"page size"`
+	// font family (html and mom), eg Georgia, and size in points (html,
+	// latex and mom)
+	Font     string `mdson:"font"`
+	FontSize int    `mdson:"font size"`
+	// LaTeX package setting the font, eg lmodern
+	FontPackage string `mdson:"font package"`
+	// prefix headings with their section numbers, eg 1.2 (md, html and mom);
+	// LaTeX numbers sections itself
+	NumberHeadings bool `mdson:"number headings"`
+}
+
+// checkNames returns an error if PageSize, Font or FontPackage, which are
+// written into the output as is, contain characters other than letters,
+// digits, spaces, hyphens and underscores
+func (cfg TransformerConfig) checkNames() error {
+	for _, opt := range []struct{ name, value string }{
+		{"page size", cfg.PageSize}, {"font", cfg.Font}, {"font package", cfg.FontPackage}} {
+		for _, r := range opt.value {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_", r) {
+				return fmt.Errorf("%s '%s': invalid character %q", opt.name, opt.value, r)
+			}
+		}
+	}
+	return nil
 }
 
-//TransformerConfig holds basic output control vars 
-type TransformerConfig struct{
-	Indent string
-	ListMaker string 
-	TabWidth int
+// lineEnding returns the line ending set by LineEnding
+func (cfg TransformerConfig) lineEnding() string {
+	if strings.EqualFold(cfg.LineEnding, "lf") {
+		return "\n"
+	}
+	return EOL
+}
+
+// headingNumbers computes the section numbers of headings in document order
+type headingNumbers []int
+
+// next returns the number of the next heading at level, eg "1.2 ", or an
+// empty string if numbering is off
+func (hn *headingNumbers) next(level int, on bool) string {
+	if !on || level < 1 {
+		return ""
+	}
+	for len(*hn) < level {
+		*hn = append(*hn, 0)
+	}
+	*hn = (*hn)[:level]
+	(*hn)[level-1]++
+	nums := make([]string, level)
+	for i, n := range *hn {
+		nums[i] = strconv.Itoa(n)
+	}
+	return strings.Join(nums, ".") + " "
 }
 
 func DefaultTransformerConfig() TransformerConfig{
@@ -43,6 +116,7 @@ var _ Transformer=&MDTransformer{}
 type MDTransformer struct {
 	printer
 	TransformerConfig 
+	numbers *headingNumbers
 }
 
 func NewMDTransformer(cfg TransformerConfig) MDTransformer {
@@ -54,33 +128,33 @@ func NewMDTransformer(cfg TransformerConfig) MDTransformer {
 
 func (m MDTransformer) printNode(n Node) {
 	// m.ctx.Log("printNode():", n)
-	switch n := n.(type) {
-	case *ttBlock:
-		if n.Level() > 0 { // donot print root's title
-			m.println(strings.Repeat("#", n.Level()) + " " + n.Value())
+	Inspect(n, func(n Node) bool {
+		switch n := n.(type) {
+		case *ttBlock:
+			if n.Level() > 0 { // donot print root's title
+				m.println(strings.Repeat("#", n.Level()) + " " + m.numbers.next(n.Level(), m.NumberHeadings) + n.Value())
+			}
+			m.Indent = strings.Repeat(" ", n.Level()*m.TabWidth)
+		case *ttList:
+			m.println(n.Value())
+			m.Indent = strings.Repeat(" ", n.Level()*m.TabWidth)
+		case *ttListItem:
+			m.print(m.Indent + m.ListMaker)
+			m.println(n.Value())
+		case Custom:
+			m.custom(n)
+		default:
+			m.println(n.Value())
 		}
-		m.Indent = strings.Repeat(" ", n.Level()* m.TabWidth)
-		for _, n := range n.Children() {
-			m.printNode(n)
-		}
-	case *ttList:
-		m.println(n.Value())
-		m.Indent = strings.Repeat(" ", n.Level()* m.TabWidth)
-		for _, n := range n.Children() {
-			m.printNode(n)
-		}
-	case *ttListItem:
-		m.print(m.Indent+ m.ListMaker)
-		m.println(n.Value())
-	default:
-		m.println(n.Value())
-	}
+		return true
+	})
 }
 
 // TODO: check for writing errors
 func (m MDTransformer) Transform(w io.Writer, doc *Document) error {
-	m.printer = printer{w} 
+	m.printer = printer{w: w, format: "md", plugins: doc.plugins(), eol: m.lineEnding(), hookErr: new(error)}
+	m.numbers = &headingNumbers{}
 	m.printNode(doc.root)
 	// m.w.Flush()
-	return nil
+	return m.renderErr()
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/drgo/booker/tu"
@@ -19,7 +20,11 @@ func test_md_transform(t *testing.T, doc *Document) {
 }
 
 func TestTransformMD(t *testing.T) {
-	doc, err := ctx.ParseFile("", specs)
+	test_md_transform(t, parseSpecs(t))
+}
+
+func TestMDBlock(t *testing.T) {
+	doc, err := ctx.ParseFile("test/blocks.md", nil)
 	tu.Equal(t, err, nil)
 	if err != nil {
 		return
@@ -27,11 +32,16 @@ func TestTransformMD(t *testing.T) {
 	test_md_transform(t, doc)
 }
 
-func TestMDBlock(t *testing.T) {
-	doc, err := ctx.ParseFile("test/blocks.md", nil)
+func TestMDPreservesHeadingCase(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader("# Introduction\n## The CHF Causes\ntext\n"))
 	tu.Equal(t, err, nil)
 	if err != nil {
 		return
 	}
-	test_md_transform(t, doc)
+	intro := doc.root.NthChild(0).(*ttBlock)
+	tu.Equal(t, intro.Key(), "introduction")
+	tu.Equal(t, intro.getChildByName("the chf causes") != nil, true)
+	var sb strings.Builder
+	NewMDTransformer(DefaultTransformerConfig()).Transform(&sb, doc)
+	tu.Equal(t, sb.String(), "# Introduction"+EOL+"## The CHF Causes"+EOL+"text"+EOL)
 }
@@ -0,0 +1,129 @@
+package mdson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var _ Transformer = &MDSonTransformer{}
+
+// MDSonTransformer writes a document back as MDSon source, eg after editing
+// it. Parsing its output gives the same tree. Blocks are written with the
+// heading level implied by their depth, attributes follow their block's
+// heading, and values spanning several lines use <<< >>>. Since a block
+// extends to the next heading, child blocks are written after the text and
+// lists of their parent. Comments are not part of the tree and are lost.
+type MDSonTransformer struct {
+	printer
+	TransformerConfig
+	// if set, braces are written as is so that references are kept, eg in
+	// generated documents; otherwise they are escaped so that evaluated text
+	// is not evaluated again
+	Raw bool
+	// the first value that could not be written
+	err error
+}
+
+// NewMDSonTransformer returns a Transformer that produces MDSon
+func NewMDSonTransformer(cfg TransformerConfig) *MDSonTransformer {
+	return &MDSonTransformer{TransformerConfig: cfg}
+}
+
+// Format writes doc as MDSon source keeping references in text and values
+func Format(w io.Writer, doc *Document) error {
+	m := NewMDSonTransformer(DefaultTransformerConfig())
+	m.Raw = true
+	return m.Transform(w, doc)
+}
+
+func (m *MDSonTransformer) Transform(w io.Writer, doc *Document) error {
+	m.printer = printer{w: w, format: "mdson", plugins: doc.plugins(), eol: m.lineEnding(), hookErr: new(error)}
+	m.err = nil
+	m.printBlock(doc.root, 0)
+	if m.err != nil {
+		return m.err
+	}
+	return m.renderErr()
+}
+
+var braceEscaper = strings.NewReplacer("{", `\{`, "}", `\}`)
+
+// text escapes s unless Raw is set
+func (m *MDSonTransformer) text(s string) string {
+	if m.Raw {
+		return s
+	}
+	return braceEscaper.Replace(s)
+}
+
+func (m *MDSonTransformer) printBlock(blk BlockNode, level int) {
+	if level > 0 {
+		m.println(strings.Repeat("#", level) + " " + strings.TrimSpace(blk.Value()))
+	}
+	for _, a := range blk.Attribs() {
+		m.printAttrib(a.Key(), a.Value())
+	}
+	for _, c := range blk.Children() {
+		switch c.Kind() {
+		case LtList:
+			m.println("~" + strings.TrimSpace(c.Value()))
+			for _, item := range c.(BlockNode).Children() {
+				if item.Kind() == LtListItem {
+					m.println("- " + m.text(strings.TrimSpace(item.Value())))
+				}
+			}
+		case LtTextLine:
+			s := m.text(c.Value())
+			if m.needsEscape(s) {
+				s = `\` + s
+			}
+			m.println(s)
+		case LtEmpty:
+			m.println("")
+		case LtCustom:
+			if c, ok := c.(Custom); ok && !m.custom(c) {
+				for _, line := range c.Directive().Source() {
+					m.println(line)
+				}
+			}
+		}
+	}
+	// a block extends to the next heading of the same or a lower level, so
+	// child blocks are written after the block's other content
+	for _, c := range blk.Children() {
+		if c.Kind() == LtBlock {
+			m.printBlock(c.(BlockNode), level+1)
+		}
+	}
+}
+
+// needsEscape reports whether the text line s would be parsed as something
+// else, or lose a backslash, unless preceded by a backslash
+func (m *MDSonTransformer) needsEscape(s string) bool {
+	return s != "" && (strings.IndexByte(escapableMarkers, s[0]) != -1 ||
+		pluginFor(m.plugins, s) != nil || isEscaped(s, m.plugins))
+}
+
+func (m *MDSonTransformer) printAttrib(key, value string) {
+	value = m.text(value)
+	if strings.TrimSpace(value) == "" { // the value would be read from the next line
+		m.println("." + key + ": <<>>")
+		return
+	}
+	if !strings.Contains(value, "\n") && !strings.HasPrefix(value, "<<") {
+		m.println("." + key + ": " + value)
+		return
+	}
+	if strings.Contains(value, ">>>") { // it would end the value early
+		if m.err == nil {
+			m.err = fmt.Errorf("attribute '%s': a value written between <<< and >>> cannot contain '>>>'", key)
+		}
+		return
+	}
+	m.println("." + key + ": <<<")
+	for _, line := range strings.Split(value, "\n") {
+		m.println(line)
+	}
+	m.println(">>>")
+}
@@ -0,0 +1,136 @@
+package mdson
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+var _ Transformer = &LaTeXTransformer{}
+
+// latexSections are the sectioning commands used for heading levels 1 to 5;
+// deeper headings use the last one
+var latexSections = [...]string{"section", "subsection", "subsubsection", "paragraph", "subparagraph"}
+
+// latexEscaper escapes the characters that have a special meaning in LaTeX
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	"{", `\{`,
+	"}", `\}`,
+	"#", `\#`,
+	"$", `\$`,
+	"%", `\%`,
+	"&", `\&`,
+	"_", `\_`,
+	"~", `\textasciitilde{}`,
+	"^", `\textasciicircum{}`,
+)
+
+// LaTeXTransformer renders a document as a LaTeX article. Blocks become
+// sections, ~lists become itemize environments and text lines are written as
+// is after escaping LaTeX's special characters. The front matter title,
+// subtitle, authors and date fill in the \maketitle fields.
+type LaTeXTransformer struct {
+	printer
+	TransformerConfig
+	// if set, only the body of the document is written
+	Fragment bool
+	// LaTeX document class; article if empty
+	Class string
+}
+
+// NewLaTeXTransformer returns a Transformer that produces LaTeX
+func NewLaTeXTransformer(cfg TransformerConfig) *LaTeXTransformer {
+	return &LaTeXTransformer{TransformerConfig: cfg}
+}
+
+func (l *LaTeXTransformer) Transform(w io.Writer, doc *Document) error {
+	if err := l.checkNames(); err != nil {
+		return err
+	}
+	l.printer = printer{w: w, format: "latex", plugins: doc.plugins(), eol: l.lineEnding(), hookErr: new(error)}
+	if !l.Fragment {
+		class := l.Class
+		if class == "" {
+			class = "article"
+		}
+		var classOpts []string
+		if l.FontSize > 0 {
+			classOpts = append(classOpts, strconv.Itoa(l.FontSize)+"pt")
+		}
+		if size := strings.ToLower(l.PageSize); size != "" {
+			if !strings.HasSuffix(size, "paper") {
+				size += "paper"
+			}
+			classOpts = append(classOpts, size)
+		}
+		if len(classOpts) > 0 {
+			class = "[" + strings.Join(classOpts, ",") + "]{" + class
+		} else {
+			class = "{" + class
+		}
+		l.println(`\documentclass` + class + `}`)
+		l.println(`\usepackage[utf8]{inputenc}`)
+		if l.FontPackage != "" {
+			l.println(`\usepackage{` + l.FontPackage + `}`)
+		}
+		m := doc.meta
+		if m != nil && m.Title != "" {
+			title := latexEscaper.Replace(m.Title)
+			if m.Subtitle != "" {
+				title += `\\ \large ` + latexEscaper.Replace(m.Subtitle)
+			}
+			l.println(`\title{` + title + `}`)
+			authors := make([]string, len(m.Authors))
+			for i, a := range m.Authors {
+				authors[i] = latexEscaper.Replace(a)
+			}
+			l.println(`\author{` + strings.Join(authors, ` \and `) + `}`)
+			if !m.Date.IsZero() {
+				l.println(`\date{` + m.Date.Format("2 January 2006") + `}`)
+			}
+		}
+		l.println(`\begin{document}`)
+		if m != nil && m.Title != "" {
+			l.println(`\maketitle`)
+		}
+	}
+	l.printNode(doc.root)
+	if !l.Fragment {
+		l.println(`\end{document}`)
+	}
+	return l.renderErr()
+}
+
+func (l *LaTeXTransformer) printNode(n Node) {
+	pre := func(c *Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ttBlock:
+			if n.Level() > 0 { // donot print root's title
+				cmd := latexSections[len(latexSections)-1]
+				if n.Level() <= len(latexSections) {
+					cmd = latexSections[n.Level()-1]
+				}
+				l.println(`\` + cmd + `{` + latexEscaper.Replace(strings.TrimSpace(n.Value())) + `}`)
+			}
+		case *ttList:
+			l.println(`\begin{itemize}`)
+		case *ttListItem:
+			l.println(`\item ` + latexEscaper.Replace(strings.TrimSpace(n.Value())))
+		case *ttTextLine:
+			l.println(latexEscaper.Replace(n.Value()))
+		case *ttEmpty:
+			l.println("")
+		case Custom:
+			l.custom(n)
+		}
+		return true
+	}
+	post := func(c *Cursor) bool {
+		if c.Node().Kind() == LtList {
+			l.println(`\end{itemize}`)
+		}
+		return true
+	}
+	Apply(n, pre, post)
+}
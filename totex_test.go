@@ -0,0 +1,38 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestTransformLaTeX(t *testing.T) {
+	src := ".title: Costs & Benefits\n.authors: A. Author, B. Author\n.date: 2023-07-12\n# Intro\n50% of {{x}} cases\n~ items\n- one_1\n## Sub\ntext\n"
+	doc, err := ctx.ParseFile("", strings.NewReader(strings.ReplaceAll(src, "{{x}}", `\{x\}`)))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	var sb strings.Builder
+	NewLaTeXTransformer(DefaultTransformerConfig()).Transform(&sb, doc)
+	want := []string{
+		`\documentclass{article}`,
+		`\usepackage[utf8]{inputenc}`,
+		`\title{Costs \& Benefits}`,
+		`\author{A. Author \and B. Author}`,
+		`\date{12 July 2023}`,
+		`\begin{document}`,
+		`\maketitle`,
+		`\section{Intro}`,
+		`50\% of \{x\} cases`,
+		`\begin{itemize}`,
+		`\item one\_1`,
+		`\end{itemize}`,
+		`\subsection{Sub}`,
+		`text`,
+		`\end{document}`,
+		"",
+	}
+	tu.Equal(t, sb.String(), strings.Join(want, EOL))
+}
@@ -28,7 +28,7 @@ const (
 
 func (lt LineType) String() string {
 	//[...] creates an array rather than a slice
-	if lt >=LtCustom {
+	if lt >LtCustom {
 		return "Unknown"
 	}	
 	return [...]string{"Read Error", "Syntax Error", "EOF",
@@ -59,6 +59,24 @@ type BlockNode interface {
 	NthChild(idx int) Node
 	AddChild(n Node) BlockNode
 	UpdateChild(idx int, n Node) BlockNode
+	// returns the value of an attribute declared in this block
+	Attrib(key string) (string, bool)
+	addAttrib(att *ttAttrib)
+	attribNodes() []*ttAttrib
+	setChildren(children []Node)
+	// inserts n before the child at idx; idx may equal the number of children
+	InsertChild(idx int, n Node) BlockNode
+	// removes the child at idx
+	RemoveChild(idx int) BlockNode
+	// moves the child at from so that it ends up at index to
+	MoveChild(from, to int) BlockNode
+	// returns the attributes declared in this block as nodes whose Key is
+	// the attribute's name and Value its value
+	Attribs() []Node
+	// sets the value of an attribute declared in this block, declaring it if needed
+	SetAttrib(key, value string) BlockNode
+	// removes an attribute declared in this block
+	RemoveAttrib(key string) BlockNode
 }
 
 // baseToken implements the basic token interface root of all of other tokens
@@ -151,6 +169,8 @@ func newSyntaxError(value interface{}) *ttSyntaxError {
 type ttBlock struct {
 	*ttBase
 	children []Node
+	// attributes declared in this block in source order
+	attribs []*ttAttrib
 }
 
 func newBlock(key string, level int) *ttBlock {
@@ -173,20 +193,78 @@ func (blk ttBlock) String() string {
 
 
 
-//AddChild adds a child and sets its level to parent.Level + 1
+//AddChild adds a child and sets its level to parent.Level + 1 except for
+// blocks which keep the level of their heading (see adopt)
 func (blk *ttBlock) AddChild(n Node) BlockNode {
-	n.SetLevel(blk.Level() + 1)
+	blk.adopt(n)
 	blk.children = append(blk.children, n)
 	return blk
 }
 
+// adopt sets the level of a new child n. Blocks keep the level of their
+// heading if it is deeper than blk's; other nodes, and blocks built without
+// a level, are placed one level below blk together with their descendants.
+func (blk *ttBlock) adopt(n Node) {
+	if n.Kind() == LtBlock && n.Level() > blk.Level() {
+		return
+	}
+	setLevel(n, blk.Level()+1)
+}
+
+func setLevel(n Node, level int) {
+	n.SetLevel(level)
+	if b, ok := n.(BlockNode); ok {
+		for _, c := range b.Children() {
+			if c.Kind() != LtBlock || c.Level() <= level {
+				setLevel(c, level+1)
+			}
+		}
+	}
+}
+
+// InsertChild inserts n before the child at idx placing it and its
+// descendants one level below blk; it panics if idx is out of range
+func (blk *ttBlock) InsertChild(idx int, n Node) BlockNode {
+	setLevel(n, blk.Level()+1)
+	blk.children = append(blk.children, nil)
+	copy(blk.children[idx+1:], blk.children[idx:])
+	blk.children[idx] = n
+	return blk
+}
+
+// RemoveChild removes the child at idx; it panics if idx is out of range
+func (blk *ttBlock) RemoveChild(idx int) BlockNode {
+	blk.children = append(blk.children[:idx], blk.children[idx+1:]...)
+	return blk
+}
+
+// MoveChild moves the child at from to index to, shifting the children in
+// between; it panics if either index is out of range
+func (blk *ttBlock) MoveChild(from, to int) BlockNode {
+	n := blk.children[from]
+	if from < to {
+		copy(blk.children[from:to], blk.children[from+1:to+1])
+	} else {
+		copy(blk.children[to+1:from+1], blk.children[to:from])
+	}
+	blk.children[to] = n
+	return blk
+}
+
 
 func (blk *ttBlock) UpdateChild(idx int, n Node) BlockNode {
 	blk.children[idx] =n 
 	return blk
 }
 
+// Key returns the normalised block name used for lookups and references;
+// Value returns the name as written in the heading
+func (blk ttBlock) Key() string {
+	return trimLower(blk.key)
+}
+
 func (blk ttBlock) getChildByName(name string) Node {
+	name = trimLower(name)
 	for _, c := range blk.children {
 		if c.Key() == name {
 			return c
@@ -195,6 +273,61 @@ func (blk ttBlock) getChildByName(name string) Node {
 	return nil
 }
 
+func (blk ttBlock) Attrib(key string) (string, bool) {
+	for _, a := range blk.attribs {
+		if a.key == key {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+func (blk ttBlock) Attribs() []Node {
+	nodes := make([]Node, len(blk.attribs))
+	for i, a := range blk.attribs {
+		nodes[i] = a
+	}
+	return nodes
+}
+
+// SetAttrib sets the value of attribute key, matched ignoring case, or
+// declares it at the end of the block's attributes
+func (blk *ttBlock) SetAttrib(key, value string) BlockNode {
+	for _, a := range blk.attribs {
+		if trimLower(a.key) == trimLower(key) {
+			a.value = value
+			return blk
+		}
+	}
+	att := newAttrib(key, value)
+	att.SetLevel(blk.Level() + 1)
+	blk.attribs = append(blk.attribs, att)
+	return blk
+}
+
+// RemoveAttrib removes attribute key, matched ignoring case, if declared
+func (blk *ttBlock) RemoveAttrib(key string) BlockNode {
+	for i, a := range blk.attribs {
+		if trimLower(a.key) == trimLower(key) {
+			blk.attribs = append(blk.attribs[:i], blk.attribs[i+1:]...)
+			break
+		}
+	}
+	return blk
+}
+
+func (blk *ttBlock) addAttrib(att *ttAttrib) {
+	blk.attribs = append(blk.attribs, att)
+}
+
+func (blk ttBlock) attribNodes() []*ttAttrib {
+	return blk.attribs
+}
+
+func (blk *ttBlock) setChildren(children []Node) {
+	blk.children = children
+}
+
 func (blk ttBlock) Children() []Node {
 	return blk.children
 }
@@ -214,6 +347,17 @@ func newAttrib(key, value string) *ttAttrib {
 		value: strings.TrimSpace(value)}
 }
 
+// Value returns the attribute's value
+func (att ttAttrib) Value() string {
+	return att.value
+}
+
+// SetValue sets the attribute's value
+func (att *ttAttrib) SetValue(s string) Node {
+	att.value = s
+	return att
+}
+
 func (att ttAttrib) String() string {
 	return att.ttBase.String() + ": " + att.value
 }
@@ -15,8 +15,9 @@ func trimLeftSpace(s string) string {
 }
 
 
-// getBlockInfo returns header name and level eg "###Document" returns "document", 3}
-// returned name is always lower-case (see parser_test.go for more details)
+// getBlockInfo returns header name and level eg "### Document" returns "Document", 3}
+// or -1 if the line is not a valid heading (eg "###Document" or "###")
+// returned name is as written; block lookups use its normalised form (see ttBlock.Key())
 //assumes that is called for a string that starts with a space followed by #*
 func getBlockInfo(line string) (string, int) {
 	// hot path
@@ -28,14 +29,14 @@ func getBlockInfo(line string) (string, int) {
 	i := 1
 	for ; i < lgth && line[i] == '#'; i++ { }
 	//next char should be space 
-	if i < lgth && line[i+1] != ' ' {
+	if i == lgth || (line[i] != ' ' && line[i] != '\t') {
 		return "", -1
 	}	
 	name := strings.TrimSpace(line[i:])
 	if name == "" { //no name, heading but invalid
 		return "", -1
 	}
-	return  trimLower(name),  i
+	return  name,  i
 }
 
 func throw(value interface{}) (*Document, error) {
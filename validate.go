@@ -0,0 +1,47 @@
+package mdson
+
+// validate runs the checks that need the whole document tree and adds any
+// problems found to the document's diagnostics
+func (doc *Document) validate() {
+	doc.validateNames(doc.root)
+}
+
+// validateNames reports blocks and lists that share a name with a sibling.
+// Names are compared ignoring case. A block is a sibling of the blocks that
+// follow it at the same heading level until a heading of a lower level
+// closes their parent, so the check also holds when headings are not
+// nested under their parent block.
+func (doc *Document) validateNames(blk BlockNode) {
+	type scope struct {
+		level int
+		name  string
+		names map[string]Node
+	}
+	scopes := []*scope{{level: blk.Level(), name: trimLower(blk.Key()), names: map[string]Node{}}}
+	for _, c := range blk.Children() {
+		cb, ok := c.(BlockNode)
+		if !ok {
+			continue
+		}
+		if c.Kind() == LtBlock {
+			// a heading closes all open headings of the same or a deeper level
+			for len(scopes) > 1 && scopes[len(scopes)-1].level >= c.Level() {
+				scopes = scopes[:len(scopes)-1]
+			}
+		}
+		parent := scopes[len(scopes)-1]
+		name := trimLower(c.Key())
+		if first, found := parent.names[name]; found {
+			if !(doc.ctx.AllowDuplicatesInLists && isArray(parent.name)) {
+				doc.addDiagnostic(c.LineNum(), SevError, "duplicate block name '%s' on lines %d and %d",
+					name, first.LineNum(), c.LineNum())
+			}
+		} else {
+			parent.names[name] = c
+		}
+		if c.Kind() == LtBlock {
+			scopes = append(scopes, &scope{level: c.Level(), name: name, names: map[string]Node{}})
+		}
+		doc.validateNames(cb)
+	}
+}
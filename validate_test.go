@@ -0,0 +1,44 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+	"github.com/drgo/core/ui"
+)
+
+const dupSrc = `# Family
+## Children List
+### Child
+.gender: male
+### child
+.gender: female
+## Address
+## ADDRESS
+`
+
+func TestValidateDuplicateNames(t *testing.T) {
+	_, err := ctx.ParseFile("", strings.NewReader(dupSrc))
+	diags, ok := err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if !ok {
+		return
+	}
+	tu.Equal(t, len(diags), 2)
+	tu.Equal(t, diags[0].Msg, "duplicate block name 'child' on lines 3 and 5")
+	tu.Equal(t, diags[1].Msg, "duplicate block name 'address' on lines 7 and 8")
+}
+
+func TestValidateDuplicatesInLists(t *testing.T) {
+	opts := DefaultOptions().SetDebug(ui.Debug(*TestDebug))
+	opts.AllowDuplicatesInLists = true
+	_, err := NewContext(opts).ParseFile("", strings.NewReader(dupSrc))
+	diags, ok := err.(Diagnostics)
+	tu.Equal(t, ok, true)
+	if !ok {
+		return
+	}
+	tu.Equal(t, len(diags), 1)
+	tu.Equal(t, diags[0].Line, 8)
+}
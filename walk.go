@@ -0,0 +1,143 @@
+package mdson
+
+// Inspect traverses the tree rooted at n in depth-first order: it calls f(n)
+// and, if f returns true and n is a BlockNode, inspects each of its children.
+// Attributes are not visited; use BlockNode.Attribs.
+func Inspect(n Node, f func(Node) bool) {
+	if !f(n) {
+		return
+	}
+	if blk, ok := n.(BlockNode); ok {
+		for _, c := range blk.Children() {
+			Inspect(c, f)
+		}
+	}
+}
+
+// An ApplyFunc is invoked by Apply for each node before and after its
+// children with a Cursor describing the node and providing operations on it.
+// Its result controls the traversal; see Apply.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses the tree rooted at root in depth-first order like go/ast's
+// astutil.Apply. For each node it calls pre, if not nil, then traverses the
+// node's children if pre returned true, then calls post, if not nil. If pre
+// returns false, the node's children are skipped and post is not called for
+// it. If post returns false, the traversal stops and Apply returns.
+//
+// pre and post may change the tree using the Cursor. Nodes replacing the
+// current one in pre are traversed instead of it; nodes inserted before or
+// after it are not traversed. Apply returns root or the node that replaced it.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &ttBlock{ttBase: newBase(LtBlock, "")}
+	parent.children = []Node{root}
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+		result = parent.children[0]
+	}()
+	a := &application{pre: pre, post: post, root: parent}
+	a.apply(parent, 0, root)
+	return
+}
+
+var abort = new(int) // singleton, to signal termination of Apply
+
+// A Cursor describes a node encountered during Apply
+type Cursor struct {
+	parent BlockNode
+	index  int
+	node   Node
+	iter   *iterator
+	// the placeholder parent of the root
+	root BlockNode
+}
+
+// Node returns the current node
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the block containing the current node or nil for the root
+func (c *Cursor) Parent() BlockNode {
+	if c.parent == c.root {
+		return nil
+	}
+	return c.parent
+}
+
+// Index returns the index of the current node among its parent's children
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current node with n
+func (c *Cursor) Replace(n Node) {
+	c.parent.UpdateChild(c.index, n)
+	c.node = n
+}
+
+// Delete deletes the current node from its parent; it panics for the root
+func (c *Cursor) Delete() {
+	if c.parent == c.root {
+		panic("mdson: Cursor.Delete called for the root")
+	}
+	c.parent.RemoveChild(c.index)
+	c.iter.step--
+}
+
+// InsertBefore inserts n before the current node; it is not traversed
+func (c *Cursor) InsertBefore(n Node) {
+	if c.parent == c.root {
+		panic("mdson: Cursor.InsertBefore called for the root")
+	}
+	c.parent.InsertChild(c.index, n)
+	c.index++
+	c.iter.index++
+}
+
+// InsertAfter inserts n after the current node; it is not traversed
+func (c *Cursor) InsertAfter(n Node) {
+	if c.parent == c.root {
+		panic("mdson: Cursor.InsertAfter called for the root")
+	}
+	c.parent.InsertChild(c.index+1, n)
+	c.iter.step++
+}
+
+// iterator tracks the position in a parent's children
+type iterator struct {
+	index, step int
+}
+
+type application struct {
+	pre, post ApplyFunc
+	root      BlockNode
+	cursor    Cursor
+	iter      iterator
+}
+
+func (a *application) apply(parent BlockNode, index int, n Node) {
+	saved := a.cursor
+	a.cursor = Cursor{parent: parent, index: index, node: n, iter: &a.iter, root: a.root}
+	if a.pre != nil && !a.pre(&a.cursor) {
+		a.cursor = saved
+		return
+	}
+	n = a.cursor.node
+	if blk, ok := n.(BlockNode); ok {
+		a.applyChildren(blk)
+	}
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(abort)
+	}
+	a.cursor = saved
+}
+
+func (a *application) applyChildren(blk BlockNode) {
+	saved := a.iter
+	a.iter.index = 0
+	for a.iter.index < len(blk.Children()) {
+		a.iter.step = 1
+		a.apply(blk, a.iter.index, blk.Children()[a.iter.index])
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
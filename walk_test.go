@@ -0,0 +1,77 @@
+package mdson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drgo/booker/tu"
+)
+
+const walkSrc = "# A\na1\n~ L\n- x\n- y\n## B\nb1\nb2\n# C\nc1\n"
+
+func TestInspect(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(walkSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	var visited []string
+	Inspect(doc.Root(), func(n Node) bool {
+		visited = append(visited, strings.TrimSpace(n.Value()))
+		return n.Kind() != LtList // skip list items
+	})
+	tu.Equal(t, visited, []string{"root", "A", "a1", "L", "B", "b1", "b2", "C", "c1"})
+}
+
+func TestApply(t *testing.T) {
+	doc, err := ctx.ParseFile("", strings.NewReader(walkSrc))
+	tu.Equal(t, err, nil)
+	if err != nil {
+		return
+	}
+	var parents []string
+	var order []string
+	pre := func(c *Cursor) bool {
+		n := c.Node()
+		order = append(order, "enter "+strings.TrimSpace(n.Value()))
+		if c.Parent() != nil && n.Kind() == LtTextLine {
+			parents = append(parents, c.Parent().Value()+"/"+n.Value())
+		}
+		switch n.Value() {
+		case "a1":
+			c.Replace(NewTextLine("A1"))
+			c.InsertBefore(NewTextLine("a0"))
+			c.InsertAfter(NewTextLine("a2"))
+		case "b1":
+			c.Delete()
+		case "C":
+			return false
+		}
+		return true
+	}
+	post := func(c *Cursor) bool {
+		order = append(order, "leave "+strings.TrimSpace(c.Node().Value()))
+		return true
+	}
+	root := Apply(doc.Root(), pre, post)
+	tu.Equal(t, root, doc.Root())
+	tu.Equal(t, parents, []string{"A/a1", "B/b1", "B/b2"})
+	tu.Equal(t, strings.Join(order, ","), "enter root,enter A,enter a1,leave A1,enter L,enter x,leave x,enter y,leave y,leave L,"+
+		"enter B,enter b1,leave b1,enter b2,leave b2,leave B,leave A,enter C,leave root")
+	var sb strings.Builder
+	Format(&sb, doc)
+	tu.Equal(t, sb.String(), strings.Join([]string{"# A", "a0", "A1", "a2", "~L", "- x", "- y", "## B", "b2", "# C", "c1", ""}, EOL))
+
+	// stopping the traversal and replacing the root
+	count := 0
+	Apply(doc.Root(), nil, func(c *Cursor) bool {
+		count++
+		return c.Node().Value() != "a0"
+	})
+	tu.Equal(t, count, 1)
+	root = Apply(doc.Root(), func(c *Cursor) bool {
+		c.Replace(NewBlock("new root"))
+		return false
+	}, nil)
+	tu.Equal(t, root.Value(), "new root")
+}
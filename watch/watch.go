@@ -0,0 +1,117 @@
+// Package watch reports changes to files by polling their modification time
+// and size. Polling needs no platform support and is cheap for the few
+// hundred files of a typical book or site.
+package watch
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is the polling interval used if none is given
+const DefaultInterval = 500 * time.Millisecond
+
+// Watcher tracks a set of files. It is safe for concurrent use.
+type Watcher struct {
+	interval time.Duration
+	mu       sync.Mutex
+	files    map[string]state
+}
+
+// state is what is known of a file when it was last checked
+type state struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+func stat(name string) state {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return state{}
+	}
+	return state{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+}
+
+// differs reports whether s and old describe different versions of a file;
+// times are compared as instants since == also compares their locations
+func (s state) differs(old state) bool {
+	return s.exists != old.exists || s.size != old.size || !s.modTime.Equal(old.modTime)
+}
+
+// New returns a Watcher that polls every interval or DefaultInterval if interval <= 0
+func New(interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{interval: interval, files: make(map[string]state)}
+}
+
+// Add starts watching files; files already watched are left unchanged. Files
+// need not exist: their creation is reported as a change.
+func (w *Watcher) Add(files ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, f := range files {
+		if _, ok := w.files[f]; !ok {
+			w.files[f] = stat(f)
+		}
+	}
+}
+
+// Remove stops watching files
+func (w *Watcher) Remove(files ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, f := range files {
+		delete(w.files, f)
+	}
+}
+
+// Files returns the watched files sorted by name
+func (w *Watcher) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := make([]string, 0, len(w.files))
+	for f := range w.files {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Poll checks all watched files and returns, sorted by name, those that were
+// created, modified or removed since they were last checked
+func (w *Watcher) Poll() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var changed []string
+	for f, old := range w.files {
+		if cur := stat(f); cur.differs(old) {
+			w.files[f] = cur
+			changed = append(changed, f)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Run polls the watched files until ctx is done, calling onChange with the
+// files found changed by each poll
+func (w *Watcher) Run(ctx context.Context, onChange func(changed []string)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if changed := w.Poll(); len(changed) > 0 {
+				onChange(changed)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drgo/booker/tu"
+)
+
+func TestPoll(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.mdson"), filepath.Join(dir, "b.mdson")
+	if err := os.WriteFile(a, []byte("# A\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := New(0)
+	w.Add(a, b)
+	tu.Equal(t, w.Files(), []string{a, b})
+	tu.Equal(t, len(w.Poll()), 0)
+
+	// b is created and a changes size
+	os.WriteFile(b, []byte("# B\n"), 0o644)
+	os.WriteFile(a, []byte("# A\ntext\n"), 0o644)
+	tu.Equal(t, w.Poll(), []string{a, b})
+	tu.Equal(t, len(w.Poll()), 0)
+
+	// same size but a later modification time
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(a, later, later)
+	tu.Equal(t, w.Poll(), []string{a})
+
+	os.Remove(b)
+	tu.Equal(t, w.Poll(), []string{b})
+	w.Remove(b)
+	tu.Equal(t, w.Files(), []string{a})
+}
+
+func TestStateDiffers(t *testing.T) {
+	mod := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	s := state{exists: true, modTime: mod, size: 10}
+	tu.Equal(t, s.differs(state{exists: true, modTime: mod.In(time.FixedZone("EST", -5*3600)), size: 10}), false)
+	tu.Equal(t, s.differs(state{exists: true, modTime: mod.Add(time.Second), size: 10}), true)
+	tu.Equal(t, s.differs(state{exists: true, modTime: mod, size: 11}), true)
+	tu.Equal(t, s.differs(state{}), true)
+}